@@ -0,0 +1,151 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = BuildInfo{}
+	_ encoding.BinaryUnmarshaler = &BuildInfo{}
+)
+
+// MarshalBinary encodes the BuildInfo as a sequence of length-prefixed fields.
+//
+// Each string is written as a big-endian uint16 length followed by that many bytes.
+// The fixed fields come first, in declaration order, followed by a uint16 count of
+// Extra entries and then each entry's key and value, sorted by key. Source comes last,
+// only when set, so that records of builds without it are unchanged.
+// Strings longer than 65535 bytes cannot be encoded.
+func (b BuildInfo) MarshalBinary() ([]byte, error) {
+	if len(b.Extra) > math.MaxUint16 {
+		return nil, fmt.Errorf("%d extra fields exceed the maximum of %d", len(b.Extra), math.MaxUint16)
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	for _, f := range b.binaryFields() {
+		if data, err = appendBinaryString(data, *f); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := make([]string, 0, len(b.Extra))
+	for k := range b.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	data = appendBinaryLength(data, len(keys))
+	for _, k := range keys {
+		if data, err = appendBinaryString(data, k); err != nil {
+			return nil, err
+		}
+		if data, err = appendBinaryString(data, b.Extra[k]); err != nil {
+			return nil, err
+		}
+	}
+	if b.Source != "" {
+		if data, err = appendBinaryString(data, b.Source); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a BuildInfo produced by MarshalBinary.
+func (b *BuildInfo) UnmarshalBinary(data []byte) error {
+	var (
+		res BuildInfo
+		err error
+	)
+	for i, f := range res.binaryFields() {
+		if *f, data, err = readBinaryString(data); err != nil {
+			return fmt.Errorf("invalid BuildInfo record, field %d: %v", i, err)
+		}
+	}
+
+	var count int
+	if count, data, err = readBinaryLength(data); err != nil {
+		return fmt.Errorf("invalid BuildInfo record, extra field count: %v", err)
+	}
+	for i := 0; i < count; i++ {
+		var k, v string
+		if k, data, err = readBinaryString(data); err != nil {
+			return fmt.Errorf("invalid BuildInfo record, extra field %d key: %v", i, err)
+		}
+		if v, data, err = readBinaryString(data); err != nil {
+			return fmt.Errorf("invalid BuildInfo record, extra field %d value: %v", i, err)
+		}
+		if res.Extra == nil {
+			res.Extra = make(map[string]string, count)
+		}
+		res.Extra[k] = v
+	}
+
+	if len(data) != 0 {
+		if res.Source, data, err = readBinaryString(data); err != nil {
+			return fmt.Errorf("invalid BuildInfo record, source: %v", err)
+		}
+	}
+	if len(data) != 0 {
+		return fmt.Errorf("invalid BuildInfo record, %d trailing bytes", len(data))
+	}
+
+	*b = res
+	return nil
+}
+
+// binaryFields returns the fixed fields of the binary record, in encoding order.
+func (b *BuildInfo) binaryFields() []*string {
+	return []*string{&b.Version, &b.GitRevision, &b.GolangVersion, &b.BuildStatus, &b.GitTag}
+}
+
+func appendBinaryLength(data []byte, l int) []byte {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], uint16(l))
+	return append(data, buf[:]...)
+}
+
+func appendBinaryString(data []byte, s string) ([]byte, error) {
+	if len(s) > math.MaxUint16 {
+		return nil, fmt.Errorf("field of length %d exceeds the maximum of %d bytes", len(s), math.MaxUint16)
+	}
+	return append(appendBinaryLength(data, len(s)), s...), nil
+}
+
+func readBinaryLength(data []byte) (int, []byte, error) {
+	if len(data) < 2 {
+		return 0, nil, fmt.Errorf("missing length")
+	}
+	return int(binary.BigEndian.Uint16(data)), data[2:], nil
+}
+
+func readBinaryString(data []byte) (string, []byte, error) {
+	l, data, err := readBinaryLength(data)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(data) < l {
+		return "", nil, fmt.Errorf("needs %d bytes but only %d remain", l, len(data))
+	}
+	return string(data[:l]), data[l:], nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		in   BuildInfo
+	}{
+		{"empty", BuildInfo{}},
+		{"init", Info},
+		{
+			"all specified",
+			BuildInfo{
+				Version:       "1.11.2",
+				GitRevision:   "3a136c90ec5e308f236e0d7ebb5c4c5e405217f4",
+				GolangVersion: "go1.16.5",
+				BuildStatus:   "Clean",
+				GitTag:        "1.11.2",
+			},
+		},
+		{"max field", BuildInfo{Version: strings.Repeat("v", 65535)}},
+		{"extra", BuildInfo{Version: "1.11.2", Extra: map[string]string{"pipeline": "1234", "ticket": "ABC-1", "empty": ""}}},
+		{"source", BuildInfo{Version: "1.11.2", Source: "ci", Extra: map[string]string{"pipeline": "1234"}}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			data, err := v.in.MarshalBinary()
+			if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			var got BuildInfo
+			if err := got.UnmarshalBinary(data); err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			if !reflect.DeepEqual(got, v.in) {
+				t.Errorf("got %v; want %v", got, v.in)
+			}
+		})
+	}
+}
+
+func TestBinaryErrors(t *testing.T) {
+	if _, err := (BuildInfo{GitTag: strings.Repeat("t", 65536)}).MarshalBinary(); err == nil {
+		t.Errorf("Expected failure for oversized field, got success")
+	}
+	if _, err := (BuildInfo{Extra: map[string]string{"k": strings.Repeat("v", 65536)}}).MarshalBinary(); err == nil {
+		t.Errorf("Expected failure for oversized extra field, got success")
+	}
+
+	good, _ := BuildInfo{Version: "1.11.2"}.MarshalBinary()
+	cases := []struct {
+		name string
+		in   []byte
+	}{
+		{"nil", nil},
+		{"truncated count", good[:len(good)-1]},
+		{"truncated extra", append(good[:len(good)-2], 0, 1)},
+		{"truncated value", good[:4]},
+		{"trailing bytes", append(append([]byte{}, good...), 0)},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got := BuildInfo{Version: "untouched"}
+			if err := got.UnmarshalBinary(v.in); err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if got.Version != "untouched" {
+				t.Errorf("BuildInfo modified on failure: %v", got)
+			}
+		})
+	}
+}
+
+func TestBinaryGob(t *testing.T) {
+	in := BuildInfo{Version: "1.11.2", GitRevision: "abc123", GolangVersion: "go1.16.5", BuildStatus: "Clean", GitTag: "1.11.2"}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatalf("Got %v, expected success", err)
+	}
+	var got BuildInfo
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("Got %v, expected success", err)
+	}
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("got %v; want %v", got, in)
+	}
+}
@@ -16,7 +16,6 @@ package version
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"strings"
 
@@ -31,6 +30,61 @@ type Version struct {
 	DataPlaneVersion *[]ProxyInfo `json:"dataPlaneVersion,omitempty" yaml:"dataPlaneVersion,omitempty"`
 }
 
+// FullVersionReport combines client, control plane and data plane versions in a single
+// document. Unlike Version, every section is always present: missing sections are
+// marshaled as empty arrays rather than omitted or null.
+type FullVersionReport struct {
+	Client    BuildInfo   `json:"clientVersion"`
+	Mesh      MeshInfo    `json:"meshVersion"`
+	DataPlane []ProxyInfo `json:"dataPlaneVersion"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r FullVersionReport) MarshalJSON() ([]byte, error) {
+	// fullVersionReport has no methods, avoiding infinite recursion
+	type fullVersionReport FullVersionReport
+	res := fullVersionReport(r)
+	if res.Mesh == nil {
+		res.Mesh = MeshInfo{}
+	}
+	if res.DataPlane == nil {
+		res.DataPlane = []ProxyInfo{}
+	}
+	return json.Marshal(res)
+}
+
+// outputFormats are the values accepted by the --output flag of the version command, in the
+// order they are listed in its help.
+var outputFormats = []string{"yaml", "json"}
+
+// OutputFormats returns the values accepted by the --output flag of the version command, for
+// building flag help and validation. Leaving the flag empty selects the default text output,
+// whose detail is controlled by --short instead.
+func OutputFormats() []string {
+	return append([]string(nil), outputFormats...)
+}
+
+// describeOutputFormats lists the output formats for humans, as in "'yaml' or 'json'".
+func describeOutputFormats() string {
+	quoted := make([]string, 0, len(outputFormats))
+	for _, format := range outputFormats {
+		quoted = append(quoted, "'"+format+"'")
+	}
+	if len(quoted) < 2 {
+		return strings.Join(quoted, "")
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
+func isOutputFormat(output string) bool {
+	for _, format := range outputFormats {
+		if output == format {
+			return true
+		}
+	}
+	return false
+}
+
 // GetRemoteVersionFunc is the function protoype to be passed to CobraOptions so that it is
 // called when invoking `cmd version`
 type (
@@ -68,11 +122,12 @@ func CobraCommandWithOptions(options CobraOptions) *cobra.Command {
 		Use:   "version",
 		Short: "Prints out build version information",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if output != "" && output != "yaml" && output != "json" {
-				return errors.New(`--output must be 'yaml' or 'json'`)
+			if output != "" && !isOutputFormat(output) {
+				return fmt.Errorf("--output must be %s", describeOutputFormats())
 			}
 
-			version.ClientVersion = &Info
+			clientVersion := Get()
+			version.ClientVersion = &clientVersion
 
 			if options.GetRemoteVersion != nil && remote {
 				remoteVersion, serverErr = options.GetRemoteVersion()
@@ -131,7 +186,7 @@ func CobraCommandWithOptions(options CobraOptions) *cobra.Command {
 	}
 
 	cmd.Flags().BoolVarP(&short, "short", "s", false, "Use --short=false to generate full version information")
-	cmd.Flags().StringVarP(&output, "output", "o", "", "One of 'yaml' or 'json'.")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "One of "+describeOutputFormats()+".")
 	if options.GetRemoteVersion != nil {
 		cmd.Flags().BoolVar(&remote, "remote", false, "Use --remote=false to suppress control plane check")
 	}
@@ -109,15 +109,15 @@ func TestOpts(t *testing.T) {
 var meshEmptyVersion = MeshInfo{}
 
 var meshInfoSingleVersion = MeshInfo{
-	{"Pilot", BuildInfo{"1.2.0", "gitSHA123", "go1.10", "Clean", "tag"}},
-	{"Injector", BuildInfo{"1.2.0", "gitSHAabc", "go1.10.1", "Modified", "tag"}},
-	{"Citadel", BuildInfo{"1.2.0", "gitSHA321", "go1.11.0", "Clean", "tag"}},
+	{"Pilot", BuildInfo{Version: "1.2.0", GitRevision: "gitSHA123", GolangVersion: "go1.10", BuildStatus: "Clean", GitTag: "tag"}},
+	{"Injector", BuildInfo{Version: "1.2.0", GitRevision: "gitSHAabc", GolangVersion: "go1.10.1", BuildStatus: "Modified", GitTag: "tag"}},
+	{"Citadel", BuildInfo{Version: "1.2.0", GitRevision: "gitSHA321", GolangVersion: "go1.11.0", BuildStatus: "Clean", GitTag: "tag"}},
 }
 
 var meshInfoMultiVersion = MeshInfo{
-	{"Pilot", BuildInfo{"1.0.0", "gitSHA123", "go1.10", "Clean", "1.0.0"}},
-	{"Injector", BuildInfo{"1.0.1", "gitSHAabc", "go1.10.1", "Modified", "1.0.1"}},
-	{"Citadel", BuildInfo{"1.2", "gitSHA321", "go1.11.0", "Clean", "1.2"}},
+	{"Pilot", BuildInfo{Version: "1.0.0", GitRevision: "gitSHA123", GolangVersion: "go1.10", BuildStatus: "Clean", GitTag: "1.0.0"}},
+	{"Injector", BuildInfo{Version: "1.0.1", GitRevision: "gitSHAabc", GolangVersion: "go1.10.1", BuildStatus: "Modified", GitTag: "1.0.1"}},
+	{"Citadel", BuildInfo{Version: "1.2", GitRevision: "gitSHA321", GolangVersion: "go1.11.0", BuildStatus: "Clean", GitTag: "1.2"}},
 }
 
 func mockRemoteMesh(meshInfo *MeshInfo, err error) GetRemoteVersionFunc {
@@ -171,7 +171,7 @@ func TestVersion(t *testing.T) {
 			args: strings.Split("version --remote=false --short=false", " "),
 			expectedRegexp: regexp.MustCompile("version.BuildInfo{Version:\"unknown\", GitRevision:\"unknown\", " +
 				"GolangVersion:\"go1.([0-9+?(\\.)?]+)(rc[0-9]?)?(beta[0-9]?)?\", " +
-				"BuildStatus:\"unknown\", GitTag:\"unknown\"}"),
+				"BuildStatus:\"unknown\", GitTag:\"unknown\", Source:\"local\"}"),
 		},
 		{ // case 1 client-side only, short output
 			args:           strings.Split("version -s --remote=false", " "),
@@ -182,6 +182,7 @@ func TestVersion(t *testing.T) {
 			expectedRegexp: regexp.MustCompile("clientVersion:\n" +
 				"  golang_version: go1.([0-9+?(\\.)?]+)(rc[0-9]?)?(beta[0-9]?)?\n" +
 				"  revision: unknown\n" +
+				"  source: local\n" +
 				"  status: unknown\n" +
 				"  tag: unknown\n" +
 				"  version: unknown\n\n"),
@@ -194,7 +195,8 @@ func TestVersion(t *testing.T) {
 				"    \"revision\": \"unknown\",\n" +
 				"    \"golang_version\": \"go1.([0-9+?(\\.)?]+)(rc[0-9]?)?(beta[0-9]?)?\",\n" +
 				"    \"status\": \"unknown\",\n" +
-				"    \"tag\": \"unknown\"\n" +
+				"    \"tag\": \"unknown\",\n" +
+				"    \"source\": \"local\"\n" +
 				"  }\n" +
 				"}\n"),
 		},
@@ -204,7 +206,7 @@ func TestVersion(t *testing.T) {
 			remoteMesh: &meshInfoMultiVersion,
 			expectedRegexp: regexp.MustCompile("client version: version.BuildInfo{Version:\"unknown\", GitRevision:\"unknown\", " +
 				"GolangVersion:\"go1.([0-9+?(\\.)?]+)(rc[0-9]?)?(beta[0-9]?)?\", " +
-				"BuildStatus:\"unknown\", GitTag:\"unknown\"}\n" +
+				"BuildStatus:\"unknown\", GitTag:\"unknown\", Source:\"local\"}\n" +
 				printMeshVersion(&meshInfoMultiVersion, rawOutputMock)),
 		},
 		{ // case 5 remote, short output
@@ -218,6 +220,7 @@ func TestVersion(t *testing.T) {
 			expectedRegexp: regexp.MustCompile("clientVersion:\n" +
 				"  golang_version: go1.([0-9+?(\\.)?]+)(rc[0-9]?)?(beta[0-9]?)?\n" +
 				"  revision: unknown\n" +
+				"  source: local\n" +
 				"  status: unknown\n" +
 				"  tag: unknown\n" +
 				"  version: unknown\n" + printMeshVersion(&meshInfoMultiVersion, yamlOutputMock)),
@@ -231,7 +234,8 @@ func TestVersion(t *testing.T) {
 				"    \"revision\": \"unknown\",\n" +
 				"    \"golang_version\": \"go1.([0-9+?(\\.)?]+)(rc[0-9]?)?(beta[0-9]?)?\",\n" +
 				"    \"status\": \"unknown\",\n" +
-				"    \"tag\": \"unknown\"\n" +
+				"    \"tag\": \"unknown\",\n" +
+				"    \"source\": \"local\"\n" +
 				"  },\n" +
 				printMeshVersion(&meshInfoMultiVersion, jsonOutputMock)),
 		},
@@ -290,3 +294,80 @@ control plane version: 1.2.0
 		})
 	}
 }
+
+func TestFullVersionReport(t *testing.T) {
+	client := BuildInfo{Version: "VER", GitRevision: "GITREV", GolangVersion: "GOLANGVER", BuildStatus: "STATUS", GitTag: "TAG"}
+	clientJSON := `"clientVersion":{"version":"VER","revision":"GITREV","golang_version":"GOLANGVER","status":"STATUS","tag":"TAG"}`
+
+	cases := []struct {
+		name string
+		in   FullVersionReport
+		want string
+	}{
+		{
+			"empty sections",
+			FullVersionReport{Client: client},
+			`{` + clientJSON + `,"meshVersion":[],"dataPlaneVersion":[]}`,
+		},
+		{
+			"all sections",
+			FullVersionReport{
+				Client:    client,
+				Mesh:      MeshInfo{{Component: "Pilot", Info: BuildInfo{Version: "1.2.0"}}},
+				DataPlane: []ProxyInfo{{ID: "pod.ns", IstioVersion: "1.2.0"}},
+			},
+			`{` + clientJSON + `,` +
+				`"meshVersion":[{"Component":"Pilot","Info":{"version":"1.2.0","revision":"","golang_version":"","status":"","tag":""}}],` +
+				`"dataPlaneVersion":[{"ID":"pod.ns","IstioVersion":"1.2.0"}]}`,
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := json.Marshal(v.in)
+			if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			if string(got) != v.want {
+				t.Errorf("got\n%s\nwant\n%s", got, v.want)
+			}
+
+			// marshaling through a pointer must produce the same document
+			if got, _ := json.Marshal(&v.in); string(got) != v.want {
+				t.Errorf("got\n%s\nwant\n%s", got, v.want)
+			}
+		})
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	formats := OutputFormats()
+	if len(formats) == 0 {
+		t.Fatalf("got no output formats")
+	}
+
+	for _, format := range formats {
+		t.Run(format, func(t *testing.T) {
+			cmd := CobraCommandWithOptions(CobraOptions{GetRemoteVersion: mockRemoteMesh(&meshInfoMultiVersion, nil)})
+			var out bytes.Buffer
+			cmd.SetOutput(&out)
+			cmd.SetArgs([]string{"version", "--remote=true", "--output", format})
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+
+			var got Version
+			if err := yaml.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			if got.ClientVersion == nil || got.MeshVersion == nil || len(*got.MeshVersion) != len(meshInfoMultiVersion) {
+				t.Errorf("got %+v; want client and mesh versions", got)
+			}
+		})
+	}
+
+	formats[0] = "modified"
+	if OutputFormats()[0] == "modified" {
+		t.Errorf("OutputFormats returned its internal slice")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	envoyVersionsMu sync.RWMutex
+	envoyVersions   = make(map[string]string)
+)
+
+// RegisterEnvoyMapping records the Envoy version shipped with an Istio version, for use by
+// EnvoyVersionFor. The Istio version may be a full version such as "1.12.1" or a release train
+// such as "1.12". The mapping changes with every release, so none is registered by default.
+// Registering a version again replaces its mapping.
+func RegisterEnvoyMapping(istioVersion, envoyVersion string) {
+	envoyVersionsMu.Lock()
+	defer envoyVersionsMu.Unlock()
+	envoyVersions[envoyMappingKey(istioVersion)] = envoyVersion
+}
+
+// EnvoyVersionFor returns the Envoy version shipped with an Istio version. A mapping for the
+// exact version is preferred over one for its release train. It returns false when neither
+// is known.
+func EnvoyVersionFor(istioVersion string) (string, bool) {
+	key := envoyMappingKey(istioVersion)
+	envoyVersionsMu.RLock()
+	defer envoyVersionsMu.RUnlock()
+	if envoy, ok := envoyVersions[key]; ok {
+		return envoy, true
+	}
+	ver, err := parseSemver(key)
+	if err != nil {
+		return "", false
+	}
+	envoy, ok := envoyVersions[fmt.Sprintf("%d.%d", ver.major, ver.minor)]
+	return envoy, ok
+}
+
+// envoyMappingKey removes a leading "v" and build metadata from valid versions.
+func envoyMappingKey(istioVersion string) string {
+	if ver, ok := cleanVersion(istioVersion); ok {
+		return ver
+	}
+	return strings.TrimSpace(istioVersion)
+}
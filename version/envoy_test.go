@@ -0,0 +1,60 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"testing"
+)
+
+func TestEnvoyVersionFor(t *testing.T) {
+	defer func() {
+		envoyVersionsMu.Lock()
+		defer envoyVersionsMu.Unlock()
+		envoyVersions = make(map[string]string)
+	}()
+	RegisterEnvoyMapping("1.11", "1.19")
+	RegisterEnvoyMapping("1.12", "1.20")
+	RegisterEnvoyMapping("1.13", "1.21")
+	RegisterEnvoyMapping("v1.12.9+build.1", "1.20.1")
+
+	cases := []struct {
+		istioVersion string
+		wantOK       bool
+		want         string
+	}{
+		{"1.11.2", true, "1.19"},
+		{"v1.12.0-rc.1", true, "1.20"},
+		{"1.12", true, "1.20"},
+		{"1.12.9", true, "1.20.1"},
+		{"1.13.0", true, "1.21"},
+		{"1.5.0", false, ""},
+		{"unknown", false, ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.istioVersion, func(t *testing.T) {
+			got, ok := EnvoyVersionFor(v.istioVersion)
+			if ok != v.wantOK || got != v.want {
+				t.Errorf("got %q, %v; want %q, %v", got, ok, v.want, v.wantOK)
+			}
+		})
+	}
+}
+
+func TestEnvoyVersionForUnregistered(t *testing.T) {
+	if got, ok := EnvoyVersionFor("1.11.2"); ok {
+		t.Errorf("got %q, %v; want no mapping by default", got, ok)
+	}
+}
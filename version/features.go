@@ -0,0 +1,62 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"sync"
+
+	"istio.io/pkg/log"
+)
+
+var (
+	featuresMu sync.RWMutex
+	// features maps feature names to the minimum version supporting them.
+	features = make(map[string]string)
+)
+
+// RegisterFeature records the minimum version supporting the named feature, for use by
+// SupportsNamed. Registering a name again replaces its minimum version.
+func RegisterFeature(name, minVersion string) {
+	featuresMu.Lock()
+	defer featuresMu.Unlock()
+	features[name] = minVersion
+}
+
+// SupportsFeature reports whether the build is at least at minVersion, ignoring build
+// metadata. Unparseable versions are never supported.
+func (b BuildInfo) SupportsFeature(minVersion string) bool {
+	min, err := parseSemver(minVersion)
+	if err != nil {
+		return false
+	}
+	ver, err := parseSemver(b.Version)
+	if err != nil {
+		return false
+	}
+	return ver.compare(min) >= 0
+}
+
+// SupportsNamed reports whether the build supports a feature registered with RegisterFeature.
+// Unregistered features are not supported, and are logged at debug level.
+func (b BuildInfo) SupportsNamed(name string) bool {
+	featuresMu.RLock()
+	minVersion, ok := features[name]
+	featuresMu.RUnlock()
+	if !ok {
+		log.Debugf("version: unknown feature %q", name)
+		return false
+	}
+	return b.SupportsFeature(minVersion)
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"testing"
+)
+
+func TestSupportsFeature(t *testing.T) {
+	cases := []struct {
+		version    string
+		minVersion string
+		want       bool
+	}{
+		{"1.11.2", "1.11.0", true},
+		{"1.11.2", "1.11.2", true},
+		{"1.11.2+build.1", "1.11.2", true},
+		{"1.11.2", "1.12", false},
+		{"1.12.0-rc.1", "1.12.0", false},
+		{"unknown", "1.11.0", false},
+		{"1.11.2", "latest", false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version+" for "+v.minVersion, func(t *testing.T) {
+			if got := (BuildInfo{Version: v.version}).SupportsFeature(v.minVersion); got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestSupportsNamed(t *testing.T) {
+	defer func() {
+		featuresMu.Lock()
+		defer featuresMu.Unlock()
+		delete(features, "telemetry-v2")
+		delete(features, "bad-version")
+	}()
+	RegisterFeature("telemetry-v2", "1.11.0")
+	RegisterFeature("bad-version", "latest")
+
+	cases := []struct {
+		version string
+		feature string
+		want    bool
+	}{
+		{"1.11.2", "telemetry-v2", true},
+		{"1.10.5", "telemetry-v2", false},
+		{"1.11.2", "bad-version", false},
+		{"1.11.2", "unregistered", false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version+" "+v.feature, func(t *testing.T) {
+			if got := (BuildInfo{Version: v.version}).SupportsNamed(v.feature); got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+
+	RegisterFeature("telemetry-v2", "1.12.0")
+	if (BuildInfo{Version: "1.11.2"}).SupportsNamed("telemetry-v2") {
+		t.Errorf("got true after raising the minimum version; want false")
+	}
+}
@@ -0,0 +1,511 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"hash/fnv"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// unsafePathCharRegexp matches characters not kept by TempDirName.
+var unsafePathCharRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// ANSI escape sequences used by StringColored.
+const (
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiRed    = "\x1b[31m"
+	ansiReset  = "\x1b[0m"
+)
+
+// colorEnabled reports whether StringColored emits color; it is a variable for testing.
+var colorEnabled = func() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// executable returns the path of the running binary; it is a variable for testing.
+var executable = os.Executable
+
+// Banner produces a boxed, multi-line banner with the product name and String(),
+// sized to fit its content, counted in runes. It is meant for opt-in startup output.
+//
+// This looks like:
+//
+// ```
+// +----------------------+
+// | Istio                |
+// | 1.11.2-3a136c9-Clean |
+// +----------------------+
+// ```
+func (b BuildInfo) Banner() string {
+	lines := []string{productName, b.String()}
+
+	width := 0
+	for _, line := range lines {
+		if n := utf8.RuneCountInString(line); n > width {
+			width = n
+		}
+	}
+
+	border := "+" + strings.Repeat("-", width+2) + "+\n"
+	var sb strings.Builder
+	sb.WriteString(border)
+	for _, line := range lines {
+		sb.WriteString("| " + line + strings.Repeat(" ", width-utf8.RuneCountInString(line)) + " |\n")
+	}
+	sb.WriteString(border)
+	return sb.String()
+}
+
+// StringVerbose produces version info with a level of detail chosen by the caller:
+//
+//   - 0 (or less): `<version>`
+//   - 1: `<version>-<git revision>`
+//   - 2: `<version>-<git revision>-<build status>`, the same as String
+//   - 3 (or more): the same as LongForm
+func (b BuildInfo) StringVerbose(level int) string {
+	switch {
+	case level <= 0:
+		return b.Version
+	case level == 1:
+		return fmt.Sprintf("%v-%v", b.Version, b.GitRevision)
+	case level == 2:
+		return b.String()
+	}
+	return b.LongForm()
+}
+
+// supportPayloadFieldLength is the maximum length of each escaped field value in a support
+// payload, which keeps payloads within 128 bytes.
+const supportPayloadFieldLength = 40
+
+// SupportPayload returns a compact, single-line, URL-safe encoding of the version, revision
+// and build status, suitable for embedding in a QR code. It looks like:
+//
+// ```
+// r=3a136c90ec5e308f236e0d7ebb5c4c5e405217f4&s=Clean&v=1.11.2
+// ```
+//
+// Each value is truncated, on a character boundary, so that it takes at most 40 bytes once
+// escaped, which guarantees that the payload is at most 128 bytes whatever the values contain.
+// Use ParseSupportPayload to decode it.
+func (b BuildInfo) SupportPayload() string {
+	return url.Values{
+		"v": {truncateEscaped(b.Version, supportPayloadFieldLength)},
+		"r": {truncateEscaped(b.GitRevision, supportPayloadFieldLength)},
+		"s": {truncateEscaped(b.BuildStatus, supportPayloadFieldLength)},
+	}.Encode()
+}
+
+// truncateEscaped returns the longest prefix of s, cut on a UTF-8 character boundary, whose
+// query escaping is at most max bytes long.
+func truncateEscaped(s string, max int) string {
+	size := 0
+	for i := 0; i < len(s); {
+		_, n := utf8.DecodeRuneInString(s[i:])
+		size += len(url.QueryEscape(s[i : i+n]))
+		if size > max {
+			return s[:i]
+		}
+		i += n
+	}
+	return s
+}
+
+// ParseSupportPayload decodes a payload produced by SupportPayload. Only the version,
+// revision and build status are populated.
+func ParseSupportPayload(payload string) (BuildInfo, error) {
+	values, err := url.ParseQuery(strings.TrimSpace(payload))
+	if err != nil {
+		return BuildInfo{}, fmt.Errorf("invalid support payload: %v", err)
+	}
+	if _, ok := values["v"]; !ok {
+		return BuildInfo{}, fmt.Errorf("invalid support payload %q: missing version", payload)
+	}
+	return BuildInfo{
+		Version:     values.Get("v"),
+		GitRevision: values.Get("r"),
+		BuildStatus: values.Get("s"),
+	}, nil
+}
+
+// datadogTagLength is the maximum length of a Datadog tag.
+const datadogTagLength = 200
+
+// DatadogTags returns the build information as Datadog `key:value` tags, using the
+// `version` and `git.commit.sha` keys from Datadog's unified service tagging. Values are
+// lowercased, characters other than alphanumerics, "_", "-", ":", "." and "/" are replaced
+// with "_", and tags are truncated to 200 characters. Empty values are omitted.
+func (b BuildInfo) DatadogTags() []string {
+	tags := []string{}
+	for _, kv := range [][2]string{
+		{"version", b.Version},
+		{"git.commit.sha", b.GitRevision},
+	} {
+		value := strings.TrimSpace(kv[1])
+		if value == "" {
+			continue
+		}
+		tag := kv[0] + ":" + datadogTagValue(value)
+		if len(tag) > datadogTagLength {
+			tag = tag[:datadogTagLength]
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func datadogTagValue(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		case strings.ContainsRune("_-:./", r):
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// StringColored produces String with the version highlighted using ANSI colors: green for
+// clean releases, yellow for clean pre-releases, and red for modified builds and unknown or
+// unparseable versions. The output is plain String when the NO_COLOR environment variable is
+// set to a non-empty value, or when standard output is not a terminal.
+func (b BuildInfo) StringColored() string {
+	if !colorEnabled() {
+		return b.String()
+	}
+
+	color := ansiRed
+	if ver, err := parseSemver(b.Version); err == nil && strings.EqualFold(b.BuildStatus, "Clean") {
+		color = ansiGreen
+		if ver.prerelease != "" {
+			color = ansiYellow
+		}
+	}
+	return fmt.Sprintf("%s%v%s-%v-%v", color, b.Version, ansiReset, b.GitRevision, b.BuildStatus)
+}
+
+// unknownTrainColor is the ColorHex of builds whose version cannot be parsed.
+const unknownTrainColor = "#808080"
+
+// goldenAngle is the angle, in degrees, between the hues of consecutive minor versions in
+// ColorHex. Successive multiples of it stay well apart around the color wheel.
+const goldenAngle = 137.50776405003785
+
+// ColorHex returns a stable "#rrggbb" color for the build's release train, so that all
+// 1.11.x builds share a color distinct from 1.12.x. The hue, in degrees, is
+// (fnv32a(major) + minor*137.50776405003785) modulo 360, where fnv32a(major) is the 32-bit
+// FNV-1a hash of the decimal major version; stepping minors by the golden angle keeps nearby
+// trains far apart on the color wheel. Saturation is 65% and lightness 50%, converted from HSL
+// to RGB with each channel rounded to the nearest integer. Builds with an unparseable version
+// are gray.
+func (b BuildInfo) ColorHex() string {
+	ver, err := parseSemver(b.Version)
+	if err != nil {
+		return unknownTrainColor
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strconv.Itoa(ver.major)))
+	hue := math.Mod(float64(h.Sum32())+float64(ver.minor)*goldenAngle, 360)
+	const saturation, lightness = 0.65, 0.5
+
+	chroma := (1 - math.Abs(2*lightness-1)) * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	var r, g, bl float64
+	switch {
+	case hue < 60:
+		r, g = chroma, x
+	case hue < 120:
+		r, g = x, chroma
+	case hue < 180:
+		g, bl = chroma, x
+	case hue < 240:
+		g, bl = x, chroma
+	case hue < 300:
+		r, bl = x, chroma
+	default:
+		r, bl = chroma, x
+	}
+	m := lightness - chroma/2
+	channel := func(v float64) int {
+		return int(math.Round((v + m) * 255))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", channel(r), channel(g), channel(bl))
+}
+
+// WithExecutable produces String followed by the path of the running binary, to tell apart
+// several installed copies. The path is "unknown" if it cannot be determined. It is kept out
+// of BuildInfo itself since it is runtime, not build, information.
+//
+// This looks like:
+//
+// ```
+// 1.11.2-3a136c90ec5e308f236e0d7ebb5c4c5e405217f4-Clean (/usr/local/bin/istioctl)
+// ```
+func (b BuildInfo) WithExecutable() string {
+	return fmt.Sprintf("%s (%s)", b.String(), executablePath())
+}
+
+// CodeComment produces a provenance header for generated files, naming the running binary
+// and its build. Every line starts with commentPrefix, such as "//" or "#".
+//
+// This looks like:
+//
+// ```
+// // Generated by mixgen 1.11.2 (3a136c90ec5e308f236e0d7ebb5c4c5e405217f4).
+// // DO NOT EDIT.
+// ```
+func (b BuildInfo) CodeComment(commentPrefix string) string {
+	binary := executablePath()
+	if binary != "unknown" {
+		binary = filepath.Base(binary)
+	}
+
+	lines := []string{
+		fmt.Sprintf("Generated by %s %s (%s).", binary, b.Version, b.GitRevision),
+		"DO NOT EDIT.",
+	}
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(strings.TrimSpace(commentPrefix+" "+line) + "\n")
+	}
+	return sb.String()
+}
+
+// INI produces the build information as an INI section named section, using the JSON keys.
+// As in JSON, source is omitted when empty. Extra entries follow as extra.<key>, sorted by key.
+// Values that are empty, have surrounding whitespace, or contain characters special to INI
+// (;, #, =, quotes, backslashes or control characters) are double-quoted with Go escaping, as
+// are extra keys containing whitespace, brackets or any of those characters, so that no entry
+// can spill into another line or section. An empty section emits the keys without a section
+// header.
+//
+// This looks like:
+//
+// ```
+// [istio]
+// version = 1.11.2
+// revision = 3a136c90ec5e308f236e0d7ebb5c4c5e405217f4
+// golang_version = go1.16.7
+// status = Clean
+// tag = 1.11.2
+// ```
+func (b BuildInfo) INI(section string) string {
+	var sb strings.Builder
+	if section != "" {
+		sb.WriteString("[" + section + "]\n")
+	}
+	write := func(key, value string) {
+		sb.WriteString(key + " = " + iniValue(value) + "\n")
+	}
+	write("version", b.Version)
+	write("revision", b.GitRevision)
+	write("golang_version", b.GolangVersion)
+	write("status", b.BuildStatus)
+	write("tag", b.GitTag)
+	if b.Source != "" {
+		write("source", b.Source)
+	}
+
+	keys := make([]string, 0, len(b.Extra))
+	for key := range b.Extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		write(iniKey("extra."+key), b.Extra[key])
+	}
+	return sb.String()
+}
+
+// iniKey double-quotes keys that are not plain INI keys.
+func iniKey(s string) string {
+	if strings.ContainsAny(s, ";#=\"'\\[]") || strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func iniValue(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, ";#=\"'\\") ||
+		strings.IndexFunc(s, unicode.IsControl) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// TempDirName returns a directory name identifying the build, for build and test artifacts:
+// the prefix, the normalized version and the abbreviated revision joined by dashes, such as
+// `prefix-1.11.2-3a136c9`. A version that is not a valid semantic version, including empty
+// and "unknown" ones, is replaced with "dev", and an unknown revision is left out, as is an
+// empty prefix. Every character other than ASCII letters, digits, '.', '_' and '-' is replaced
+// with '_'. The name is safe in paths on all platforms: it is never made of dots alone, such
+// as "." or "..", since the version part is always present.
+func (b BuildInfo) TempDirName(prefix string) string {
+	n := b.Normalize()
+
+	var parts []string
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+	if _, err := parseSemver(n.Version); err != nil {
+		parts = append(parts, "dev")
+	} else {
+		parts = append(parts, n.Version)
+	}
+	if rev := shortRevision(n.GitRevision); rev != "" {
+		parts = append(parts, rev)
+	}
+	return unsafePathCharRegexp.ReplaceAllString(strings.Join(parts, "-"), "_")
+}
+
+// ViperDefaults returns the build information keyed for viper.SetDefault, so that it can be
+// read back from configuration: `<prefix>.version`, `<prefix>.revision`,
+// `<prefix>.golang_version`, `<prefix>.status`, `<prefix>.tag`, `<prefix>.source` when Source is
+// set, and `<prefix>.extra.<key>` for each Extra entry. Keys are unprefixed when prefix is empty.
+// The map itself does not depend on viper.
+func (b BuildInfo) ViperDefaults(prefix string) map[string]interface{} {
+	key := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + "." + name
+	}
+
+	res := map[string]interface{}{
+		key("version"):        b.Version,
+		key("revision"):       b.GitRevision,
+		key("golang_version"): b.GolangVersion,
+		key("status"):         b.BuildStatus,
+		key("tag"):            b.GitTag,
+	}
+	if b.Source != "" {
+		res[key("source")] = b.Source
+	}
+	for k, v := range b.Extra {
+		res[key("extra."+k)] = v
+	}
+	return res
+}
+
+// GenerateGoSource returns a gofmt-formatted Go file for package pkg declaring
+// `var Snapshot = version.BuildInfo{...}` with the build information reported by Get, so that
+// a build step can freeze it into generated code. Source and Extra are only included when set,
+// with Extra entries sorted by key so that the output is stable. It is an error for pkg not to
+// be a valid Go identifier.
+func GenerateGoSource(pkg string) (string, error) {
+	if !token.IsIdentifier(pkg) {
+		return "", fmt.Errorf("invalid package name %q", pkg)
+	}
+	b := Get()
+
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "// Code generated by istio.io/pkg/version. DO NOT EDIT.\n\n")
+	_, _ = fmt.Fprintf(&sb, "package %s\n\nimport \"istio.io/pkg/version\"\n\n", pkg)
+	_, _ = fmt.Fprintf(&sb, "// Snapshot is the build information captured when this file was generated.\n")
+	_, _ = fmt.Fprintf(&sb, "var Snapshot = version.BuildInfo{\n")
+	_, _ = fmt.Fprintf(&sb, "Version: %q,\nGitRevision: %q,\nGolangVersion: %q,\nBuildStatus: %q,\nGitTag: %q,\n",
+		b.Version, b.GitRevision, b.GolangVersion, b.BuildStatus, b.GitTag)
+	if b.Source != "" {
+		_, _ = fmt.Fprintf(&sb, "Source: %q,\n", b.Source)
+	}
+	if len(b.Extra) > 0 {
+		keys := make([]string, 0, len(b.Extra))
+		for k := range b.Extra {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		_, _ = fmt.Fprintf(&sb, "Extra: map[string]string{\n")
+		for _, k := range keys {
+			_, _ = fmt.Fprintf(&sb, "%q: %q,\n", k, b.Extra[k])
+		}
+		_, _ = fmt.Fprintf(&sb, "},\n")
+	}
+	_, _ = fmt.Fprintf(&sb, "}\n")
+
+	src, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format generated source: %v", err)
+	}
+	return string(src), nil
+}
+
+// envOverrides are the environment variables changing how this package renders output.
+var envOverrides = []string{"NO_COLOR"}
+
+// DoctorReport produces a multi-line report of the running binary for bug reports: the build
+// information reported by Get in long form, the Go runtime, the OS and architecture, the path
+// of the executable and any environment variables set that change this package's output.
+// Data that cannot be determined is reported as "unknown" or "none".
+//
+// This looks like:
+//
+// ```
+// version: version.BuildInfo{Version:"1.11.2", GitRevision:"3a136c9...", ...}
+// go: go1.16.7 (GOMAXPROCS 8)
+// platform: linux/amd64
+// executable: /usr/local/bin/istioctl
+// environment: none
+// ```
+func DoctorReport() string {
+	var env []string
+	for _, name := range envOverrides {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	if len(env) == 0 {
+		env = []string{"none"}
+	}
+
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "version: %s\n", Get().LongForm())
+	_, _ = fmt.Fprintf(&sb, "go: %s (GOMAXPROCS %d)\n", runtime.Version(), runtime.GOMAXPROCS(0))
+	_, _ = fmt.Fprintf(&sb, "platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	_, _ = fmt.Fprintf(&sb, "executable: %s\n", executablePath())
+	_, _ = fmt.Fprintf(&sb, "environment: %s\n", strings.Join(env, " "))
+	return sb.String()
+}
+
+func executablePath() string {
+	path, err := executable()
+	if err != nil || path == "" {
+		return "unknown"
+	}
+	return path
+}
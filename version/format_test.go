@@ -0,0 +1,589 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+)
+
+func TestBanner(t *testing.T) {
+	cases := []struct {
+		name string
+		in   BuildInfo
+		want string
+	}{
+		{
+			"short",
+			BuildInfo{Version: "1", GitRevision: "a", BuildStatus: "b"},
+			"+-------+\n" +
+				"| Istio |\n" +
+				"| 1-a-b |\n" +
+				"+-------+\n",
+		},
+		{
+			"long",
+			BuildInfo{Version: "1.11.2", GitRevision: "3a136c9", BuildStatus: "Clean"},
+			"+----------------------+\n" +
+				"| Istio                |\n" +
+				"| 1.11.2-3a136c9-Clean |\n" +
+				"+----------------------+\n",
+		},
+		{
+			"non-ASCII",
+			BuildInfo{Version: "1.11.2-ünï", GitRevision: "3a136c9", BuildStatus: "Clean"},
+			"+--------------------------+\n" +
+				"| Istio                    |\n" +
+				"| 1.11.2-ünï-3a136c9-Clean |\n" +
+				"+--------------------------+\n",
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.in.Banner(); got != v.want {
+				t.Errorf("got\n%s\nwant\n%s", got, v.want)
+			}
+		})
+	}
+}
+
+func TestStringVerbose(t *testing.T) {
+	in := BuildInfo{Version: "VER", GitRevision: "GITREV", GolangVersion: "GOLANGVER", BuildStatus: "STATUS", GitTag: "TAG"}
+	long := `version.BuildInfo{Version:"VER", GitRevision:"GITREV", GolangVersion:"GOLANGVER", BuildStatus:"STATUS", GitTag:"TAG"}`
+
+	cases := []struct {
+		level int
+		want  string
+	}{
+		{-1, "VER"},
+		{0, "VER"},
+		{1, "VER-GITREV"},
+		{2, "VER-GITREV-STATUS"},
+		{3, long},
+		{4, long},
+	}
+
+	for _, v := range cases {
+		t.Run(fmt.Sprint(v.level), func(t *testing.T) {
+			if got := in.StringVerbose(v.level); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestSupportPayload(t *testing.T) {
+	cases := []struct {
+		name string
+		in   BuildInfo
+		want string
+	}{
+		{
+			"release",
+			BuildInfo{Version: "1.11.2", GitRevision: "3a136c90ec5e308f236e0d7ebb5c4c5e405217f4", BuildStatus: "Clean", GitTag: "1.11.2"},
+			"r=3a136c90ec5e308f236e0d7ebb5c4c5e405217f4&s=Clean&v=1.11.2",
+		},
+		{
+			"escaped",
+			BuildInfo{Version: "1.12.0-rc.1+build 5", GitRevision: "unknown", BuildStatus: "Modified"},
+			"r=unknown&s=Modified&v=1.12.0-rc.1%2Bbuild+5",
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got := v.in.SupportPayload()
+			if got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+			if len(got) > 128 {
+				t.Errorf("payload of %d bytes exceeds budget", len(got))
+			}
+
+			parsed, err := ParseSupportPayload(got)
+			if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			want := BuildInfo{Version: v.in.Version, GitRevision: v.in.GitRevision, BuildStatus: v.in.BuildStatus}
+			if parsed.String() != want.String() {
+				t.Errorf("got %v; want %v", parsed, want)
+			}
+		})
+	}
+
+	for _, long := range []BuildInfo{
+		{Version: strings.Repeat("1", 100), GitRevision: strings.Repeat("a", 100), BuildStatus: strings.Repeat("s", 100)},
+		{Version: strings.Repeat("ü", 100), GitRevision: strings.Repeat("/&", 100), BuildStatus: strings.Repeat("状態", 100)},
+		{Version: strings.Repeat("\xff", 100), GitRevision: "a" + strings.Repeat("€", 100), BuildStatus: strings.Repeat(" ", 100)},
+	} {
+		got := long.SupportPayload()
+		if len(got) > 128 {
+			t.Errorf("payload of %d bytes exceeds budget: %q", len(got), got)
+		}
+		parsed, err := ParseSupportPayload(got)
+		if err != nil {
+			t.Fatalf("Got %v, expected success", err)
+		}
+		for _, field := range []struct{ got, in string }{
+			{parsed.Version, long.Version},
+			{parsed.GitRevision, long.GitRevision},
+			{parsed.BuildStatus, long.BuildStatus},
+		} {
+			if !strings.HasPrefix(field.in, field.got) || (utf8.ValidString(field.in) && !utf8.ValidString(field.got)) {
+				t.Errorf("got %q; want a prefix of %q cut on a character boundary", field.got, field.in)
+			}
+		}
+	}
+
+	for _, bad := range []string{"", "r=abc", "v=%zz"} {
+		if _, err := ParseSupportPayload(bad); err == nil {
+			t.Errorf("Expected failure for %q, got success", bad)
+		}
+	}
+}
+
+func TestDatadogTags(t *testing.T) {
+	cases := []struct {
+		name string
+		in   BuildInfo
+		want []string
+	}{
+		{
+			"release",
+			BuildInfo{Version: "1.11.2", GitRevision: "3A136C9"},
+			[]string{"version:1.11.2", "git.commit.sha:3a136c9"},
+		},
+		{
+			"sanitized",
+			BuildInfo{Version: "1.12.0-rc.1+build 5", GitRevision: "unknown"},
+			[]string{"version:1.12.0-rc.1_build_5", "git.commit.sha:unknown"},
+		},
+		{
+			"truncated",
+			BuildInfo{Version: strings.Repeat("1", 300)},
+			[]string{"version:" + strings.Repeat("1", 192)},
+		},
+		{"empty", BuildInfo{}, []string{}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.in.DatadogTags(); !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestWithExecutable(t *testing.T) {
+	in := BuildInfo{Version: "1.11.2", GitRevision: "abc123", BuildStatus: "Clean"}
+
+	path, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Got %v, expected success", err)
+	}
+	if got, want := in.WithExecutable(), "1.11.2-abc123-Clean ("+path+")"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	defer func(f func() (string, error)) { executable = f }(executable)
+	executable = func() (string, error) { return "", errors.New("not supported") }
+	if got, want := in.WithExecutable(), "1.11.2-abc123-Clean (unknown)"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestCodeComment(t *testing.T) {
+	defer func(f func() (string, error)) { executable = f }(executable)
+	executable = func() (string, error) { return "/usr/local/bin/mixgen", nil }
+
+	in := BuildInfo{Version: "1.11.2", GitRevision: "abc123", BuildStatus: "Clean"}
+	cases := []struct {
+		prefix string
+		want   string
+	}{
+		{"//", "// Generated by mixgen 1.11.2 (abc123).\n// DO NOT EDIT.\n"},
+		{"#", "# Generated by mixgen 1.11.2 (abc123).\n# DO NOT EDIT.\n"},
+		{"", "Generated by mixgen 1.11.2 (abc123).\nDO NOT EDIT.\n"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.prefix, func(t *testing.T) {
+			if got := in.CodeComment(v.prefix); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+
+	executable = func() (string, error) { return "", errors.New("not supported") }
+	if got, want := in.CodeComment("//"), "// Generated by unknown 1.11.2 (abc123).\n// DO NOT EDIT.\n"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestINI(t *testing.T) {
+	full := BuildInfo{
+		Version:       "1.11.2",
+		GitRevision:   "abc123",
+		GolangVersion: "go1.16.7",
+		BuildStatus:   "Clean",
+		GitTag:        "1.11.2",
+	}
+
+	cases := []struct {
+		name    string
+		in      BuildInfo
+		section string
+		want    string
+	}{
+		{
+			"section",
+			full,
+			"istio",
+			"[istio]\nversion = 1.11.2\nrevision = abc123\ngolang_version = go1.16.7\nstatus = Clean\ntag = 1.11.2\n",
+		},
+		{
+			"no section",
+			full,
+			"",
+			"version = 1.11.2\nrevision = abc123\ngolang_version = go1.16.7\nstatus = Clean\ntag = 1.11.2\n",
+		},
+		{
+			"escaped",
+			BuildInfo{
+				Version:     "1.11.2",
+				GitRevision: "abc;123",
+				BuildStatus: " Clean",
+				GitTag:      "a=\"b\"\n",
+				Extra:       map[string]string{"ticket": "ABC-1", "builder": "#ci"},
+			},
+			"istio",
+			"[istio]\nversion = 1.11.2\nrevision = \"abc;123\"\ngolang_version = \"\"\nstatus = \" Clean\"\n" +
+				`tag = "a=\"b\"\n"` + "\nextra.builder = \"#ci\"\nextra.ticket = ABC-1\n",
+		},
+		{
+			"escaped extra keys",
+			BuildInfo{Version: "1.11.2", Extra: map[string]string{"a = b\n[evil]": "x", "c;d": "y", "e f": "z"}},
+			"",
+			"version = 1.11.2\nrevision = \"\"\ngolang_version = \"\"\nstatus = \"\"\ntag = \"\"\n" +
+				`"extra.a = b\n[evil]" = x` + "\n" + `"extra.c;d" = y` + "\n" + `"extra.e f" = z` + "\n",
+		},
+		{
+			"source",
+			BuildInfo{Version: "1.11.2", Source: "ci", Extra: map[string]string{"ticket": "ABC-1"}},
+			"",
+			"version = 1.11.2\nrevision = \"\"\ngolang_version = \"\"\nstatus = \"\"\ntag = \"\"\nsource = ci\nextra.ticket = ABC-1\n",
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.in.INI(v.section); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestTempDirName(t *testing.T) {
+	cases := []struct {
+		name   string
+		in     BuildInfo
+		prefix string
+		want   string
+	}{
+		{"release", BuildInfo{Version: "1.11.2", GitRevision: "3A136C90ec5e308f"}, "istioctl", "istioctl-1.11.2-3a136c9"},
+		{"v prefix", BuildInfo{Version: " v1.11.2 ", GitRevision: "abc123"}, "istioctl", "istioctl-1.11.2-abc123"},
+		{"no prefix", BuildInfo{Version: "1.11.2", GitRevision: "abc123"}, "", "1.11.2-abc123"},
+		{"unknown", BuildInfo{Version: "unknown", GitRevision: "unknown"}, "istioctl", "istioctl-dev"},
+		{"empty", BuildInfo{}, "istioctl", "istioctl-dev"},
+		{"unsafe characters", BuildInfo{Version: "1.12.0+build.1", GitRevision: "abc/123"}, "my tool:", "my_tool_-1.12.0_build.1-abc_123"},
+		{"dot version", BuildInfo{Version: ".", GitRevision: "unknown"}, "", "dev"},
+		{"dot dot version", BuildInfo{Version: "..", GitRevision: "unknown"}, "", "dev"},
+		{"invalid version", BuildInfo{Version: "../1.12", GitRevision: "abc123"}, "istioctl", "istioctl-dev-abc123"},
+		{"dot prefix", BuildInfo{Version: ".."}, "..", "..-dev"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.in.TempDirName(v.prefix); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestStringColored(t *testing.T) {
+	defer func(f func() bool) { colorEnabled = f }(colorEnabled)
+	colorEnabled = func() bool { return true }
+
+	cases := []struct {
+		name string
+		in   BuildInfo
+		want string
+	}{
+		{"release", BuildInfo{Version: "1.11.2", GitRevision: "abc123", BuildStatus: "Clean"}, "\x1b[32m1.11.2\x1b[0m-abc123-Clean"},
+		{"prerelease", BuildInfo{Version: "1.12.0-rc.1", GitRevision: "abc123", BuildStatus: "Clean"}, "\x1b[33m1.12.0-rc.1\x1b[0m-abc123-Clean"},
+		{"modified", BuildInfo{Version: "1.11.2", GitRevision: "abc123", BuildStatus: "Modified"}, "\x1b[31m1.11.2\x1b[0m-abc123-Modified"},
+		{"unknown", BuildInfo{Version: "unknown", GitRevision: "unknown", BuildStatus: "unknown"}, "\x1b[31munknown\x1b[0m-unknown-unknown"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.in.StringColored(); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+
+	colorEnabled = func() bool { return false }
+	in := BuildInfo{Version: "1.11.2", GitRevision: "abc123", BuildStatus: "Clean"}
+	if got := in.StringColored(); got != in.String() {
+		t.Errorf("got %q; want %q", got, in.String())
+	}
+}
+
+func TestColorEnabledNoColor(t *testing.T) {
+	defer func(v string, ok bool) {
+		if ok {
+			_ = os.Setenv("NO_COLOR", v)
+		} else {
+			_ = os.Unsetenv("NO_COLOR")
+		}
+	}(os.LookupEnv("NO_COLOR"))
+
+	_ = os.Setenv("NO_COLOR", "1")
+	if colorEnabled() {
+		t.Errorf("got color enabled with NO_COLOR set")
+	}
+}
+
+func TestDoctorReport(t *testing.T) {
+	defer Snapshot()()
+	defer func(f func() (string, error)) { executable = f }(executable)
+	defer func(v string, ok bool) {
+		if ok {
+			_ = os.Setenv("NO_COLOR", v)
+		} else {
+			_ = os.Unsetenv("NO_COLOR")
+		}
+	}(os.LookupEnv("NO_COLOR"))
+
+	Set(BuildInfo{Version: "1.11.2", GitRevision: "abc123", BuildStatus: "Clean"})
+	executable = func() (string, error) { return "/usr/local/bin/istioctl", nil }
+	_ = os.Unsetenv("NO_COLOR")
+
+	want := fmt.Sprintf("version: %s\ngo: %s (GOMAXPROCS %d)\nplatform: %s/%s\nexecutable: /usr/local/bin/istioctl\nenvironment: none\n",
+		Get().LongForm(), runtime.Version(), runtime.GOMAXPROCS(0), runtime.GOOS, runtime.GOARCH)
+	if got := DoctorReport(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	executable = func() (string, error) { return "", errors.New("not supported") }
+	_ = os.Setenv("NO_COLOR", "1")
+	got := DoctorReport()
+	for _, line := range []string{"executable: unknown\n", "environment: NO_COLOR=1\n"} {
+		if !strings.Contains(got, line) {
+			t.Errorf("got\n%s\nwant it to contain %q", got, line)
+		}
+	}
+}
+
+func TestViperDefaults(t *testing.T) {
+	in := BuildInfo{
+		Version:       "1.11.2",
+		GitRevision:   "abc123",
+		GolangVersion: "go1.16.7",
+		BuildStatus:   "Clean",
+		GitTag:        "1.11.2",
+		Extra:         map[string]string{"pipeline": "1234"},
+	}
+
+	cases := []struct {
+		name   string
+		in     BuildInfo
+		prefix string
+		want   map[string]interface{}
+	}{
+		{
+			"prefix",
+			in,
+			"istio",
+			map[string]interface{}{
+				"istio.version":        "1.11.2",
+				"istio.revision":       "abc123",
+				"istio.golang_version": "go1.16.7",
+				"istio.status":         "Clean",
+				"istio.tag":            "1.11.2",
+				"istio.extra.pipeline": "1234",
+			},
+		},
+		{
+			"no prefix",
+			in,
+			"",
+			map[string]interface{}{
+				"version":        "1.11.2",
+				"revision":       "abc123",
+				"golang_version": "go1.16.7",
+				"status":         "Clean",
+				"tag":            "1.11.2",
+				"extra.pipeline": "1234",
+			},
+		},
+		{
+			"source",
+			BuildInfo{Version: "1.11.2", Source: "ci"},
+			"istio",
+			map[string]interface{}{
+				"istio.version":        "1.11.2",
+				"istio.revision":       "",
+				"istio.golang_version": "",
+				"istio.status":         "",
+				"istio.tag":            "",
+				"istio.source":         "ci",
+			},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.in.ViperDefaults(v.prefix); !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+
+	v := viper.New()
+	for key, value := range in.ViperDefaults("build.info") {
+		v.SetDefault(key, value)
+	}
+	if got := v.GetString("build.info.version"); got != in.Version {
+		t.Errorf("got %q; want %q", got, in.Version)
+	}
+	if got := v.GetStringMapString("build.info.extra"); !reflect.DeepEqual(got, in.Extra) {
+		t.Errorf("got %v; want %v", got, in.Extra)
+	}
+}
+
+func TestGenerateGoSource(t *testing.T) {
+	defer Snapshot()()
+
+	cases := []struct {
+		name string
+		info BuildInfo
+	}{
+		{"init", Info},
+		{"empty", BuildInfo{}},
+		{
+			"all specified",
+			BuildInfo{
+				Version:       "1.11.2",
+				GitRevision:   "3a136c90ec5e308f236e0d7ebb5c4c5e405217f4",
+				GolangVersion: "go1.16.5",
+				BuildStatus:   "Clean",
+				GitTag:        "1.11.2",
+				Source:        "ci",
+				Extra:         map[string]string{"ticket": "ABC-1", "pipeline": "12\"34"},
+			},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			SetProvider(fixedProvider(v.info))
+			src, err := GenerateGoSource("snapshot")
+			if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+
+			formatted, err := format.Source([]byte(src))
+			if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			if string(formatted) != src {
+				t.Errorf("got\n%s\nwant gofmt-clean\n%s", src, formatted)
+			}
+
+			f, err := parser.ParseFile(token.NewFileSet(), "snapshot.go", src, 0)
+			if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			if f.Name.Name != "snapshot" {
+				t.Errorf("got %q; want %q", f.Name.Name, "snapshot")
+			}
+			for _, value := range []string{v.info.Version, v.info.GitRevision, v.info.GolangVersion, v.info.GitTag} {
+				if !strings.Contains(src, strconv.Quote(value)) {
+					t.Errorf("got\n%s\nwant it to contain %q", src, value)
+				}
+			}
+			for k, value := range v.info.Extra {
+				want := regexp.MustCompile(regexp.QuoteMeta(strconv.Quote(k)) + `:\s+` + regexp.QuoteMeta(strconv.Quote(value)) + `,`)
+				if !want.MatchString(src) {
+					t.Errorf("got\n%s\nwant it to match %s", src, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateGoSourceInvalidPackage(t *testing.T) {
+	for _, pkg := range []string{"", "foo-bar", "1pkg", "func"} {
+		t.Run(pkg, func(t *testing.T) {
+			if src, err := GenerateGoSource(pkg); err == nil {
+				t.Errorf("Expected failure, got success:\n%s", src)
+			}
+		})
+	}
+}
+
+func TestColorHex(t *testing.T) {
+	cases := []struct {
+		version string
+		want    string
+	}{
+		{"1.10.1", "#d02dd2"},
+		{"1.11.0", "#a5d22d"},
+		{"1.11.5", "#a5d22d"},
+		{"v1.11.2+build.1", "#a5d22d"},
+		{"1.12.0", "#2d74d2"},
+		{"1.12.0-rc.1", "#2d74d2"},
+		{"2.0", "#2dd0d2"},
+		{"unknown", "#808080"},
+		{"", "#808080"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version, func(t *testing.T) {
+			if got := (BuildInfo{Version: v.version}).ColorHex(); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpcversion propagates build information in gRPC metadata. It is kept apart from
+// the version package so that binaries not using gRPC do not depend on it.
+package grpcversion
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"istio.io/pkg/version"
+)
+
+// VersionKey is the metadata key carrying the version of the calling client.
+const VersionKey = "x-istio-version"
+
+// UnaryClientInterceptor returns an interceptor adding the version of the running binary,
+// as reported by version.Get, to the outgoing metadata of every unary call under VersionKey.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, VersionKey, version.Get().Version)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// FromIncomingContext returns the client build information sent by UnaryClientInterceptor.
+// Only the Version field is populated. It returns false if the client sent no version.
+func FromIncomingContext(ctx context.Context) (version.BuildInfo, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return version.BuildInfo{}, false
+	}
+	values := md.Get(VersionKey)
+	if len(values) == 0 || values[0] == "" {
+		return version.BuildInfo{}, false
+	}
+	return version.BuildInfo{Version: values[0]}, true
+}
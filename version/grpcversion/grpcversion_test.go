@@ -0,0 +1,84 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcversion
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"istio.io/pkg/version"
+)
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	defer version.Snapshot()()
+	version.Set(version.BuildInfo{Version: "1.11.2", GitRevision: "abc123"})
+
+	var got metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		got, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "1")
+	if err := UnaryClientInterceptor()(ctx, "/svc/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("Got %v, expected success", err)
+	}
+	want := metadata.Pairs("x-request-id", "1", VersionKey, "1.11.2")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
+
+func TestFromIncomingContext(t *testing.T) {
+	cases := []struct {
+		name   string
+		ctx    context.Context
+		wantOK bool
+		want   version.BuildInfo
+	}{
+		{
+			"version",
+			metadata.NewIncomingContext(context.Background(), metadata.Pairs(VersionKey, "1.11.2")),
+			true,
+			version.BuildInfo{Version: "1.11.2"},
+		},
+		{
+			"empty version",
+			metadata.NewIncomingContext(context.Background(), metadata.Pairs(VersionKey, "")),
+			false,
+			version.BuildInfo{},
+		},
+		{
+			"other metadata",
+			metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "1")),
+			false,
+			version.BuildInfo{},
+		},
+		{"no metadata", context.Background(), false, version.BuildInfo{}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, ok := FromIncomingContext(v.ctx)
+			if ok != v.wantOK || !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %v, %v; want %v, %v", got, ok, v.want, v.wantOK)
+			}
+		})
+	}
+}
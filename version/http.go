@@ -0,0 +1,62 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ServerHeader returns a value for the HTTP Server header of the form `product/version`.
+// OS and architecture are deliberately left out to limit fingerprinting. Characters that
+// are not allowed in an RFC 7230 token are replaced with "-". An empty or blank product is
+// replaced with "Istio", so that the value always starts with a product token.
+func (b BuildInfo) ServerHeader(product string) string {
+	if product = headerToken(product); product == "" {
+		product = productName
+	}
+	ver := b.Version
+	if cleaned, ok := cleanVersion(ver); ok {
+		ver = cleaned
+	}
+	if ver = headerToken(ver); ver == "" {
+		return product
+	}
+	return product + "/" + ver
+}
+
+// WithServerHeader returns a handler that sets the Server header to the ServerHeader of the
+// current build before invoking next. A Server header already set upstream is left untouched.
+func WithServerHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if w.Header().Get("Server") == "" {
+			w.Header().Set("Server", Get().ServerHeader(productName))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// headerToken replaces characters that are not valid in an HTTP token with "-".
+func headerToken(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+			return r
+		}
+		return '-'
+	}, strings.TrimSpace(s))
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerHeader(t *testing.T) {
+	cases := []struct {
+		product string
+		version string
+		want    string
+	}{
+		{"istiod", "1.11.2", "istiod/1.11.2"},
+		{"istiod", "v1.12.0-rc.1+build.5", "istiod/1.12.0-rc.1"},
+		{"istiod", "unknown", "istiod/unknown"},
+		{"istiod", "", "istiod"},
+		{"my server", "1.11 (dev)", "my-server/1.11--dev-"},
+		{"", "1.2.3", "Istio/1.2.3"},
+		{"  ", "1.2.3", "Istio/1.2.3"},
+		{"", "", "Istio"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.product+" "+v.version, func(t *testing.T) {
+			if got := (BuildInfo{Version: v.version}).ServerHeader(v.product); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestWithServerHeader(t *testing.T) {
+	defer Snapshot()()
+	SetProvider(fixedProvider(BuildInfo{Version: "1.11.2"}))
+
+	handler := WithServerHeader(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if got := rec.Header().Get("Server"); got != "Istio/1.11.2" {
+		t.Errorf("got %q; want %q", got, "Istio/1.11.2")
+	}
+
+	rec = httptest.NewRecorder()
+	rec.Header().Set("Server", "upstream")
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if got := rec.Header().Get("Server"); got != "upstream" {
+		t.Errorf("got %q; want %q", got, "upstream")
+	}
+}
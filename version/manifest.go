@@ -0,0 +1,46 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Manifest is a provenance record combining the build information of a component with the
+// versions of key dependencies it was built with, such as "envoy" or "go". The dependencies
+// are supplied by the caller.
+type Manifest struct {
+	Build        BuildInfo         `json:"build"`
+	Dependencies map[string]string `json:"dependencies"`
+}
+
+// Marshal encodes the manifest as JSON. Dependencies are always present, as an empty object
+// when there are none, so that consumers can rely on the schema.
+func (m Manifest) Marshal() ([]byte, error) {
+	if m.Dependencies == nil {
+		m.Dependencies = map[string]string{}
+	}
+	return json.Marshal(m)
+}
+
+// ParseManifest decodes a manifest produced by Manifest.Marshal.
+func ParseManifest(data []byte) (Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, fmt.Errorf("invalid Manifest JSON: %v", err)
+	}
+	return m, nil
+}
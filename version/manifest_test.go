@@ -0,0 +1,107 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		in   Manifest
+	}{
+		{"empty", Manifest{Dependencies: map[string]string{}}},
+		{
+			"all specified",
+			Manifest{
+				Build: BuildInfo{
+					Version:       "1.11.2",
+					GitRevision:   "3a136c90ec5e308f236e0d7ebb5c4c5e405217f4",
+					GolangVersion: "go1.16.5",
+					BuildStatus:   "Clean",
+					GitTag:        "1.11.2",
+					Source:        "ci",
+				},
+				Dependencies: map[string]string{"envoy": "1.19.1", "go": "1.17"},
+			},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			data, err := v.in.Marshal()
+			if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			got, err := ParseManifest(data)
+			if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			if !reflect.DeepEqual(got, v.in) {
+				t.Errorf("got %#v; want %#v", got, v.in)
+			}
+		})
+	}
+}
+
+func TestManifestMarshal(t *testing.T) {
+	data, err := Manifest{Build: BuildInfo{Version: "1.11.2"}}.Marshal()
+	if err != nil {
+		t.Fatalf("Got %v, expected success", err)
+	}
+	want := `{"build":{"version":"1.11.2","revision":"","golang_version":"","status":"","tag":""},"dependencies":{}}`
+	if string(data) != want {
+		t.Errorf("got %s; want %s", data, want)
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	cases := []struct {
+		name       string
+		in         string
+		expectFail bool
+		want       Manifest
+	}{
+		{
+			"valid",
+			`{"build":{"version":"1.11.2","tag":"1.11.2"},"dependencies":{"envoy":"1.19.1"}}`,
+			false,
+			Manifest{Build: BuildInfo{Version: "1.11.2", GitTag: "1.11.2"}, Dependencies: map[string]string{"envoy": "1.19.1"}},
+		},
+		{"no dependencies", `{"build":{"version":"1.11.2"}}`, false, Manifest{Build: BuildInfo{Version: "1.11.2"}}},
+		{"invalid JSON", `{"build":`, true, Manifest{}},
+		{"invalid dependencies", `{"dependencies":["envoy"]}`, true, Manifest{}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := ParseManifest([]byte(v.in))
+			if v.expectFail {
+				if err == nil {
+					t.Fatal("Expected failure, got success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %#v; want %#v", got, v.want)
+			}
+		})
+	}
+}
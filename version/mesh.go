@@ -0,0 +1,469 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// maxMeshInfoLineSize bounds the length of a single line accepted by ParseMeshInfoReader.
+const maxMeshInfoLineSize = 1024 * 1024
+
+// quotedString matches a Go double-quoted string, capturing its escaped contents.
+const quotedString = `"((?:[^"\\]|\\.)*)"`
+
+var (
+	longFormRegexp = regexp.MustCompile(`^version\.BuildInfo\{Version:` + quotedString +
+		`, GitRevision:` + quotedString +
+		`, GolangVersion:` + quotedString +
+		`, BuildStatus:` + quotedString +
+		`, GitTag:` + quotedString +
+		`(?:, Source:` + quotedString + `)?` +
+		`(?:, Extra:map\[string\]string\{(.*)\})?\}$`)
+	extraPairRegexp = regexp.MustCompile(quotedString + `:` + quotedString)
+)
+
+// ParseMeshInfo parses the control plane versions printed by the `version` command.
+// See ParseMeshInfoReader for details.
+func ParseMeshInfo(s string) (MeshInfo, error) {
+	return ParseMeshInfoReader(strings.NewReader(s))
+}
+
+// ParseMeshInfoReader parses the control plane versions printed by the `version` command,
+// one `<component> version: <info>` line at a time, where <info> is either a plain version
+// (as printed with --short) or a LongForm dump. The client and data plane lines are skipped.
+// The input is streamed, so memory use is bounded by the length of the longest line.
+func ParseMeshInfoReader(r io.Reader) (MeshInfo, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMeshInfoLineSize)
+
+	res := MeshInfo{}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, " version: ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid MeshInfo input, line '%s' is not valid", line)
+		}
+		component, value := fields[0], strings.TrimSpace(fields[1])
+		if component == "client" || component == "data plane" {
+			continue
+		}
+
+		info, err := parseBuildInfoValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MeshInfo input for component '%s': %v", component, err)
+		}
+		res = append(res, ServerInfo{Component: component, Info: info})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read MeshInfo input: %v", err)
+	}
+
+	return res, nil
+}
+
+// parseBuildInfoValue parses either a LongForm dump or a plain version.
+func parseBuildInfoValue(value string) (BuildInfo, error) {
+	if !strings.HasPrefix(value, "version.BuildInfo{") {
+		return BuildInfo{Version: value}, nil
+	}
+
+	m := longFormRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return BuildInfo{}, fmt.Errorf("malformed BuildInfo '%s'", value)
+	}
+	fields := make([]string, 6)
+	for i := range fields {
+		f, err := strconv.Unquote(`"` + m[i+1] + `"`)
+		if err != nil {
+			return BuildInfo{}, err
+		}
+		fields[i] = f
+	}
+	res := BuildInfo{
+		Version:       fields[0],
+		GitRevision:   fields[1],
+		GolangVersion: fields[2],
+		BuildStatus:   fields[3],
+		GitTag:        fields[4],
+		Source:        fields[5],
+	}
+
+	for _, pair := range extraPairRegexp.FindAllStringSubmatch(m[7], -1) {
+		k, err := strconv.Unquote(`"` + pair[1] + `"`)
+		if err != nil {
+			return BuildInfo{}, err
+		}
+		v, err := strconv.Unquote(`"` + pair[2] + `"`)
+		if err != nil {
+			return BuildInfo{}, err
+		}
+		if res.Extra == nil {
+			res.Extra = make(map[string]string)
+		}
+		res.Extra[k] = v
+	}
+
+	return res, nil
+}
+
+// AllAtLeast reports whether every component in the mesh is at least at version min,
+// along with the components that are not. Components whose version cannot be parsed
+// fail the check, as do all components when min itself cannot be parsed.
+func (m MeshInfo) AllAtLeast(min string) (bool, []ServerInfo) {
+	minVer, minErr := parseSemver(min)
+
+	var failing []ServerInfo
+	for _, info := range m {
+		ver, err := parseSemver(info.Info.Version)
+		if minErr != nil || err != nil || ver.compare(minVer) < 0 {
+			failing = append(failing, info)
+		}
+	}
+
+	return len(failing) == 0, failing
+}
+
+// maxVersion returns the highest parseable component version in the mesh, as reported
+// by the component. It returns false if no component reports a parseable version.
+func (m MeshInfo) maxVersion() (string, semver, bool) {
+	versions := make([]string, 0, len(m))
+	for _, info := range m {
+		versions = append(versions, info.Info.Version)
+	}
+	return latestVersion(versions)
+}
+
+// DistinctVersions returns the distinct component versions in the mesh, in order of appearance.
+// Versions are compared as written.
+func (m MeshInfo) DistinctVersions() []string {
+	seen := make(map[string]bool)
+	var res []string
+	for _, info := range m {
+		if !seen[info.Info.Version] {
+			seen[info.Info.Version] = true
+			res = append(res, info.Info.Version)
+		}
+	}
+	return res
+}
+
+// ClientServerSkew reports whether the client version differs from any control plane
+// component, along with a warning message naming both versions. When the control plane
+// itself runs several versions, the message lists all of them rather than picking one.
+func ClientServerSkew(client BuildInfo, mesh MeshInfo) (bool, string) {
+	versions := mesh.DistinctVersions()
+	if len(versions) == 0 {
+		return false, ""
+	}
+
+	if len(versions) > 1 {
+		return true, fmt.Sprintf("client version %s differs from control plane versions %s",
+			client.Version, strings.Join(versions, ", "))
+	}
+
+	server := versions[0]
+	c, err := CompareStrict(client.Version, server)
+	switch {
+	case err != nil && client.Version == server:
+		return false, ""
+	case err != nil:
+		return true, fmt.Sprintf("client version %s differs from control plane version %s", client.Version, server)
+	case c < 0:
+		return true, fmt.Sprintf("client version %s is older than control plane version %s", client.Version, server)
+	case c > 0:
+		return true, fmt.Sprintf("client version %s is newer than control plane version %s", client.Version, server)
+	}
+	return false, ""
+}
+
+// WriteJSON writes the mesh as a JSON array to w, one component at a time, so that large
+// meshes do not need to be marshaled into a single buffer. The output is identical to
+// that of json.Marshal.
+func (m MeshInfo) WriteJSON(w io.Writer) error {
+	if m == nil {
+		_, err := io.WriteString(w, "null")
+		return err
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i, info := range m {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(info)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+// UpgradeComplete reports whether an upgrade to target has completed: every component in
+// after runs target, and at least one component in before ran an older version. Since the
+// set of components may change between snapshots, only after must be entirely at target.
+// Versions are compared ignoring build metadata; unparseable versions never match.
+func UpgradeComplete(before, after MeshInfo, target string) bool {
+	targetVer, err := parseSemver(target)
+	if err != nil || len(after) == 0 {
+		return false
+	}
+
+	for _, info := range after {
+		ver, err := parseSemver(info.Info.Version)
+		if err != nil || ver.compare(targetVer) != 0 {
+			return false
+		}
+	}
+	for _, info := range before {
+		ver, err := parseSemver(info.Info.Version)
+		if err == nil && ver.compare(targetVer) < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingComponents returns the names in expected that no component of the mesh reports,
+// matching names case-insensitively. The result is empty, but not nil, when none are missing.
+func (m MeshInfo) MissingComponents(expected []string) []string {
+	present := make(map[string]bool, len(m))
+	for _, info := range m {
+		present[strings.ToLower(info.Component)] = true
+	}
+
+	missing := []string{}
+	for _, name := range expected {
+		if !present[strings.ToLower(name)] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// CommonVersionPrefix returns the longest prefix of whole dot-separated version fields
+// shared by every component, such as "1.11." when all components run 1.11.x, or "1." when
+// they span 1.11 and 1.12. Valid versions are compared as semantic versions, so that a
+// missing patch is treated as zero ("1.11" and "1.11.2" share "1.11."); the prefix then uses
+// the parsed numbers, without any leading "v". The first component's version is returned when
+// all components agree, and "" when the major versions differ or the mesh is empty. When any
+// version is invalid, versions are compared field by field as written.
+func (m MeshInfo) CommonVersionPrefix() string {
+	if len(m) == 0 {
+		return ""
+	}
+	if prefix, ok := m.commonSemverPrefix(); ok {
+		return prefix
+	}
+
+	common, same := strings.Split(m[0].Info.Version, "."), true
+	for _, info := range m[1:] {
+		same = same && info.Info.Version == m[0].Info.Version
+		fields := strings.Split(info.Info.Version, ".")
+		n := 0
+		for n < len(common) && n < len(fields) && common[n] == fields[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	if same {
+		return m[0].Info.Version
+	}
+	if len(common) == 0 {
+		return ""
+	}
+	return strings.Join(common, ".") + "."
+}
+
+// commonSemverPrefix computes CommonVersionPrefix for a non-empty mesh of valid versions. It
+// returns false if any version is invalid.
+func (m MeshInfo) commonSemverPrefix() (string, bool) {
+	first, err := parseSemver(m[0].Info.Version)
+	if err != nil {
+		return "", false
+	}
+	firstFields := []int{first.major, first.minor, first.patch}
+
+	n, same := len(firstFields), true
+	for _, info := range m[1:] {
+		ver, err := parseSemver(info.Info.Version)
+		if err != nil {
+			return "", false
+		}
+		fields := []int{ver.major, ver.minor, ver.patch}
+		i := 0
+		for i < n && firstFields[i] == fields[i] {
+			i++
+		}
+		n = i
+		same = same && ver.prerelease == first.prerelease && ver.metadata == first.metadata
+	}
+
+	if n == len(firstFields) {
+		if same {
+			return m[0].Info.Version, true
+		}
+		// The patch field differs as written, such as "2" and "2-rc.1".
+		n--
+	}
+	var sb strings.Builder
+	for _, field := range firstFields[:n] {
+		sb.WriteString(strconv.Itoa(field) + ".")
+	}
+	return sb.String(), true
+}
+
+// Summary renders the mesh as a single human-readable line: the number of components, the
+// range of versions they run, whether they are skewed, and how many are not clean builds.
+// Valid versions are compared as semantic versions, so that "1.2" and "1.2.0" are the same
+// version and not skewed. An empty mesh is summarized as "no components".
+//
+// This looks like:
+//
+// ```
+// 3 components, versions 1.0.0–1.2 (skew), 1 not clean
+// ```
+func (m MeshInfo) Summary() string {
+	if len(m) == 0 {
+		return "no components"
+	}
+
+	parts := []string{fmt.Sprintf("%d components", len(m))}
+	if len(m) == 1 {
+		parts[0] = "1 component"
+	}
+
+	var (
+		oldestRaw string
+		oldest    semver
+		found     bool
+		unclean   int
+	)
+	for _, info := range m {
+		if !strings.EqualFold(info.Info.BuildStatus, "Clean") {
+			unclean++
+		}
+		ver, err := parseSemver(info.Info.Version)
+		if err == nil && (!found || ver.compare(oldest) < 0) {
+			oldestRaw, oldest, found = info.Info.Version, ver, true
+		}
+	}
+
+	newestRaw, newest, _ := m.maxVersion()
+	switch {
+	case !found:
+		parts = append(parts, "versions unknown")
+	case oldest.compare(newest) == 0:
+		parts = append(parts, "version "+oldestRaw)
+	default:
+		parts = append(parts, fmt.Sprintf("versions %s–%s", oldestRaw, newestRaw))
+	}
+	for _, info := range m[1:] {
+		if !sameVersion(info.Info.Version, m[0].Info.Version) {
+			parts[len(parts)-1] += " (skew)"
+			break
+		}
+	}
+
+	if unclean == 0 {
+		parts = append(parts, "all clean")
+	} else {
+		parts = append(parts, fmt.Sprintf("%d not clean", unclean))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AlertPhrase renders the control plane versions as a short phrase suitable for alert
+// annotations. A skewed mesh lists every component with its version, in mesh order, such as
+// "control plane running mixed versions: 1.11.2 (pilot), 1.12.0 (istiod)"; an aligned one reads
+// "control plane uniformly on 1.12.0". Component names are printed as reported.
+func (m MeshInfo) AlertPhrase() string {
+	versions := m.DistinctVersions()
+	switch len(versions) {
+	case 0:
+		return "no control plane components detected"
+	case 1:
+		return "control plane uniformly on " + versions[0]
+	}
+
+	entries := make([]string, 0, len(m))
+	for _, info := range m {
+		entries = append(entries, fmt.Sprintf("%s (%s)", info.Info.Version, info.Component))
+	}
+	return "control plane running mixed versions: " + strings.Join(entries, ", ")
+}
+
+// MeshSnapshot is the state of the mesh captured at a point in time.
+type MeshSnapshot struct {
+	At   time.Time
+	Mesh MeshInfo
+}
+
+// VersionSnapshot lists the distinct versions the mesh ran at a point in time.
+type VersionSnapshot struct {
+	At       time.Time
+	Versions []string
+}
+
+// VersionTimeline summarizes periodic captures of the mesh as the distinct versions running
+// at each point in time, as computed by DistinctVersions, to follow the progress of upgrades.
+// Snapshots are kept in the given order. The result is empty, but not nil, for no snapshots.
+func VersionTimeline(snapshots []MeshSnapshot) []VersionSnapshot {
+	res := make([]VersionSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		res = append(res, VersionSnapshot{At: snapshot.At, Versions: snapshot.Mesh.DistinctVersions()})
+	}
+	return res
+}
+
+// ValidateMeshInfoJSON checks that data is a JSON MeshInfo document in which every component
+// has a non-empty name and a parseable version. All problems found are reported together.
+func ValidateMeshInfoJSON(data []byte) error {
+	var mesh MeshInfo
+	if err := json.Unmarshal(data, &mesh); err != nil {
+		return fmt.Errorf("invalid MeshInfo JSON: %v", err)
+	}
+
+	var err error
+	for i, info := range mesh {
+		if strings.TrimSpace(info.Component) == "" {
+			err = multierror.Append(err, fmt.Errorf("component %d: missing name", i))
+		}
+		if _, verr := parseSemver(info.Info.Version); verr != nil {
+			err = multierror.Append(err, fmt.Errorf("component %d (%s): %v", i, info.Component, verr))
+		}
+	}
+	return err
+}
@@ -0,0 +1,469 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllAtLeast(t *testing.T) {
+	withUnknown := MeshInfo{
+		{"Pilot", BuildInfo{Version: "1.2.0"}},
+		{"Injector", BuildInfo{Version: "unknown"}},
+	}
+
+	cases := []struct {
+		name        string
+		mesh        MeshInfo
+		min         string
+		wantOK      bool
+		wantFailing []string
+	}{
+		{"all meet", meshInfoSingleVersion, "1.2.0", true, nil},
+		{"all meet older", meshInfoSingleVersion, "1.1", true, nil},
+		{"some fail", meshInfoMultiVersion, "1.0.1", false, []string{"Pilot"}},
+		{"all fail", meshInfoMultiVersion, "1.3.0", false, []string{"Pilot", "Injector", "Citadel"}},
+		{"unparseable component", withUnknown, "1.0.0", false, []string{"Injector"}},
+		{"unparseable min", meshInfoSingleVersion, "latest", false, []string{"Pilot", "Injector", "Citadel"}},
+		{"empty mesh", meshEmptyVersion, "1.0.0", true, nil},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			ok, failing := v.mesh.AllAtLeast(v.min)
+			if ok != v.wantOK {
+				t.Errorf("got %v; want %v", ok, v.wantOK)
+			}
+			var names []string
+			for _, info := range failing {
+				names = append(names, info.Component)
+			}
+			if !reflect.DeepEqual(names, v.wantFailing) {
+				t.Errorf("got failing %v; want %v", names, v.wantFailing)
+			}
+		})
+	}
+}
+
+func TestParseMeshInfoReader(t *testing.T) {
+	withExtra := MeshInfo{
+		{"Pilot", BuildInfo{Version: "1.2.0", Extra: map[string]string{"pipeline": "12\"34", "ticket": "ABC-1"}}},
+	}
+	withSource := MeshInfo{
+		{"Pilot", BuildInfo{Version: "1.2.0", Source: "ci", Extra: map[string]string{"pipeline": "1234"}}},
+	}
+	longExtra := MeshInfo{
+		{"Pilot", BuildInfo{Version: "1.2.0", Extra: map[string]string{"blob": strings.Repeat("x", 100*1024)}}},
+	}
+
+	cases := []struct {
+		name string
+		mesh *MeshInfo
+		args string
+	}{
+		{"short", &meshInfoMultiVersion, "version --short=true --remote=true"},
+		{"long", &meshInfoMultiVersion, "version --short=false --remote=true"},
+		{"long extra", &withExtra, "version --short=false --remote=true"},
+		{"long line", &longExtra, "version --short=false --remote=true"},
+		{"long source", &withSource, "version --short=false --remote=true"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			cmd := CobraCommandWithOptions(CobraOptions{GetRemoteVersion: mockRemoteMesh(v.mesh, nil)})
+			var out bytes.Buffer
+			cmd.SetOutput(&out)
+			cmd.SetArgs(strings.Split(v.args, " "))
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Got %v, expecting success", err)
+			}
+
+			want := *v.mesh
+			if strings.Contains(v.args, "--short=true") {
+				want = MeshInfo{}
+				for _, info := range *v.mesh {
+					want = append(want, ServerInfo{Component: info.Component, Info: BuildInfo{Version: info.Info.Version}})
+				}
+			}
+
+			fromReader, err := ParseMeshInfoReader(&out)
+			if err != nil {
+				t.Fatalf("Got %v, expecting success", err)
+			}
+			if !reflect.DeepEqual(fromReader, want) {
+				t.Errorf("got %v; want %v", fromReader, want)
+			}
+		})
+	}
+}
+
+func TestParseMeshInfoErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+	}{
+		{"no version", "Pilot 1.2.0"},
+		{"malformed long form", `Pilot version: version.BuildInfo{Version:"1.2.0"}`},
+		{"line too long", "Pilot version: " + strings.Repeat("1", maxMeshInfoLineSize)},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if _, err := ParseMeshInfo(v.in); err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+		})
+	}
+
+	got, err := ParseMeshInfo("")
+	if err != nil || len(got) != 0 {
+		t.Errorf("got %v, %v; want empty MeshInfo", got, err)
+	}
+}
+
+func TestClientServerSkew(t *testing.T) {
+	cases := []struct {
+		name     string
+		client   string
+		mesh     MeshInfo
+		wantSkew bool
+		wantMsg  string
+	}{
+		{"match", "1.2.0", meshInfoSingleVersion, false, ""},
+		{"match ignoring metadata", "1.2.0+build.1", meshInfoSingleVersion, false, ""},
+		{"older", "1.1.0", meshInfoSingleVersion, true, "client version 1.1.0 is older than control plane version 1.2.0"},
+		{"newer", "1.3.0", meshInfoSingleVersion, true, "client version 1.3.0 is newer than control plane version 1.2.0"},
+		{"mixed", "1.2", meshInfoMultiVersion, true, "client version 1.2 differs from control plane versions 1.0.0, 1.0.1, 1.2"},
+		{"unparseable", "unknown", meshInfoSingleVersion, true, "client version unknown differs from control plane version 1.2.0"},
+		{"unparseable match", "unknown", MeshInfo{{"Pilot", BuildInfo{Version: "unknown"}}}, false, ""},
+		{"empty mesh", "1.2.0", meshEmptyVersion, false, ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			skew, msg := ClientServerSkew(BuildInfo{Version: v.client}, v.mesh)
+			if skew != v.wantSkew {
+				t.Errorf("got %v; want %v", skew, v.wantSkew)
+			}
+			if msg != v.wantMsg {
+				t.Errorf("got %q; want %q", msg, v.wantMsg)
+			}
+		})
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestWriteJSON(t *testing.T) {
+	withExtra := MeshInfo{{"Pilot", BuildInfo{Version: "1.2.0", Extra: map[string]string{"<html>": "&"}}}}
+	cases := []struct {
+		name string
+		mesh MeshInfo
+	}{
+		{"nil", nil},
+		{"empty", meshEmptyVersion},
+		{"single", meshInfoSingleVersion[:1]},
+		{"multi", meshInfoMultiVersion},
+		{"escaping", withExtra},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			want, _ := json.Marshal(v.mesh)
+			var got bytes.Buffer
+			if err := v.mesh.WriteJSON(&got); err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			if got.String() != string(want) {
+				t.Errorf("got\n%s\nwant\n%s", got.String(), want)
+			}
+		})
+	}
+
+	if err := meshInfoMultiVersion.WriteJSON(failingWriter{}); err == nil {
+		t.Errorf("Expected failure, got success")
+	}
+}
+
+func TestUpgradeComplete(t *testing.T) {
+	partial := MeshInfo{
+		{"Pilot", BuildInfo{Version: "1.2.0"}},
+		{"Injector", BuildInfo{Version: "1.0.1"}},
+	}
+	renamed := MeshInfo{{"istiod", BuildInfo{Version: "1.2.0+build.1"}}}
+
+	cases := []struct {
+		name   string
+		before MeshInfo
+		after  MeshInfo
+		target string
+		want   bool
+	}{
+		{"completed", meshInfoMultiVersion, meshInfoSingleVersion, "1.2.0", true},
+		{"partial", meshInfoMultiVersion, partial, "1.2.0", false},
+		{"already at target", meshInfoSingleVersion, meshInfoSingleVersion, "1.2.0", false},
+		{"components changed", meshInfoMultiVersion, renamed, "1.2.0", true},
+		{"empty after", meshInfoMultiVersion, meshEmptyVersion, "1.2.0", false},
+		{"unparseable target", meshInfoMultiVersion, meshInfoSingleVersion, "latest", false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := UpgradeComplete(v.before, v.after, v.target); got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestMissingComponents(t *testing.T) {
+	cases := []struct {
+		name     string
+		mesh     MeshInfo
+		expected []string
+		want     []string
+	}{
+		{"all present", meshInfoSingleVersion, []string{"Pilot", "citadel"}, []string{}},
+		{"some missing", meshInfoSingleVersion, []string{"pilot", "Galley", "istiod"}, []string{"Galley", "istiod"}},
+		{"empty mesh", meshEmptyVersion, []string{"Pilot"}, []string{"Pilot"}},
+		{"nothing expected", meshInfoSingleVersion, nil, []string{}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got := v.mesh.MissingComponents(v.expected)
+			if got == nil || !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %#v; want %#v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestCommonVersionPrefix(t *testing.T) {
+	mesh := func(versions ...string) MeshInfo {
+		res := MeshInfo{}
+		for _, v := range versions {
+			res = append(res, ServerInfo{Component: "Pilot", Info: BuildInfo{Version: v}})
+		}
+		return res
+	}
+
+	cases := []struct {
+		name string
+		mesh MeshInfo
+		want string
+	}{
+		{"empty mesh", meshEmptyVersion, ""},
+		{"single version", meshInfoSingleVersion, "1.2.0"},
+		{"multi version", meshInfoMultiVersion, "1."},
+		{"same minor", mesh("1.11.2", "1.11.3"), "1.11."},
+		{"spanning minors", mesh("1.11.2", "1.12.0"), "1."},
+		{"differing majors", mesh("1.11.2", "2.0.0"), ""},
+		{"prerelease", mesh("1.11.2", "1.11.2-rc.1"), "1.11."},
+		{"whole fields only", mesh("1.1.0", "1.10.0"), "1."},
+		{"missing patch", mesh("1.11", "1.11.2"), "1.11."},
+		{"missing patch equal", mesh("1.2", "1.2.0"), "1.2"},
+		{"v prefix", mesh("v1.11.2", "1.11.3"), "1.11."},
+		{"invalid version", mesh("1.11.2", "1.11.x"), "1.11."},
+		{"invalid version missing patch", mesh("1.11", "1.11.x"), "1.11."},
+		{"invalid versions", mesh("unknown", "dev"), ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.mesh.CommonVersionPrefix(); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	cases := []struct {
+		name string
+		mesh MeshInfo
+		want string
+	}{
+		{"empty mesh", meshEmptyVersion, "no components"},
+		{"single version", meshInfoSingleVersion, "3 components, version 1.2.0, 1 not clean"},
+		{"multi version", meshInfoMultiVersion, "3 components, versions 1.0.0–1.2 (skew), 1 not clean"},
+		{
+			"one component",
+			MeshInfo{{"Pilot", BuildInfo{Version: "1.11.2", BuildStatus: "Clean"}}},
+			"1 component, version 1.11.2, all clean",
+		},
+		{
+			"missing patch",
+			MeshInfo{
+				{"Pilot", BuildInfo{Version: "1.2", BuildStatus: "Clean"}},
+				{"Citadel", BuildInfo{Version: "1.2.0", BuildStatus: "Clean"}},
+			},
+			"2 components, version 1.2, all clean",
+		},
+		{
+			"unparseable",
+			MeshInfo{
+				{"Pilot", BuildInfo{Version: "unknown", BuildStatus: "Clean"}},
+				{"Citadel", BuildInfo{Version: "", BuildStatus: "Clean"}},
+			},
+			"2 components, versions unknown (skew), all clean",
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.mesh.Summary(); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestAlertPhrase(t *testing.T) {
+	cases := []struct {
+		name string
+		mesh MeshInfo
+		want string
+	}{
+		{"empty mesh", meshEmptyVersion, "no control plane components detected"},
+		{"single version", meshInfoSingleVersion, "control plane uniformly on 1.2.0"},
+		{
+			"multi version",
+			meshInfoMultiVersion,
+			"control plane running mixed versions: 1.0.0 (Pilot), 1.0.1 (Injector), 1.2 (Citadel)",
+		},
+		{
+			"istiod",
+			MeshInfo{
+				{"pilot", BuildInfo{Version: "1.11.2"}},
+				{"istiod", BuildInfo{Version: "1.12.0"}},
+			},
+			"control plane running mixed versions: 1.11.2 (pilot), 1.12.0 (istiod)",
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.mesh.AlertPhrase(); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestDistinctVersions(t *testing.T) {
+	cases := []struct {
+		name string
+		mesh MeshInfo
+		want []string
+	}{
+		{"empty mesh", meshEmptyVersion, nil},
+		{"single version", meshInfoSingleVersion, []string{"1.2.0"}},
+		{"multi version", meshInfoMultiVersion, []string{"1.0.0", "1.0.1", "1.2"}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.mesh.DistinctVersions(); !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %#v; want %#v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestVersionTimeline(t *testing.T) {
+	start := time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		snapshots []MeshSnapshot
+		want      []VersionSnapshot
+	}{
+		{"no snapshots", nil, []VersionSnapshot{}},
+		{
+			"upgrade",
+			[]MeshSnapshot{
+				{At: start, Mesh: meshInfoMultiVersion},
+				{At: start.Add(time.Hour), Mesh: meshInfoSingleVersion},
+				{At: start.Add(2 * time.Hour), Mesh: meshEmptyVersion},
+			},
+			[]VersionSnapshot{
+				{At: start, Versions: []string{"1.0.0", "1.0.1", "1.2"}},
+				{At: start.Add(time.Hour), Versions: []string{"1.2.0"}},
+				{At: start.Add(2 * time.Hour)},
+			},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got := VersionTimeline(v.snapshots)
+			if got == nil || !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %#v; want %#v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestValidateMeshInfoJSON(t *testing.T) {
+	good, _ := json.Marshal(meshInfoMultiVersion)
+
+	cases := []struct {
+		name       string
+		in         string
+		wantErrors []string
+	}{
+		{"valid", string(good), nil},
+		{"empty", "[]", nil},
+		{
+			"one bad component",
+			`[{"Component":"Pilot","Info":{"version":"1.2.0"}},` +
+				`{"Component":"","Info":{"version":"unknown"}},` +
+				`{"Component":"Citadel","Info":{"version":"1.2.0"}}]`,
+			[]string{"component 1: missing name", `component 1 (): invalid version "unknown"`},
+		},
+		{"not json", "Pilot version: 1.2.0", []string{"invalid MeshInfo JSON"}},
+		{"wrong shape", `{"Component":"Pilot"}`, []string{"invalid MeshInfo JSON"}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			err := ValidateMeshInfoJSON([]byte(v.in))
+			if len(v.wantErrors) == 0 {
+				if err != nil {
+					t.Errorf("Got %v, expected success", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected failure, got success")
+			}
+			for _, want := range v.wantErrors {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("got %q; want it to contain %q", err.Error(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	migrationBoundariesMu sync.RWMutex
+	migrationBoundaries   = make(map[string]bool)
+)
+
+// RegisterMigrationBoundary records a version that performs an irreversible state migration,
+// for use by CrossesMigrationBoundary. Boundaries are product-specific, so none are
+// registered by default. Registering a version again has no effect.
+func RegisterMigrationBoundary(version string) {
+	migrationBoundariesMu.Lock()
+	defer migrationBoundariesMu.Unlock()
+	migrationBoundaries[version] = true
+}
+
+// CrossesMigrationBoundary reports whether downgrading from one version to another crosses
+// any registered migration boundary, that is a boundary newer than to but no newer than from,
+// and returns those boundaries from newest to oldest. Upgrades, unparseable versions and
+// unparseable boundaries never cross a boundary.
+func CrossesMigrationBoundary(from, to string) (bool, []string) {
+	fromVer, err := parseSemver(from)
+	if err != nil {
+		return false, nil
+	}
+	toVer, err := parseSemver(to)
+	if err != nil || fromVer.compare(toVer) <= 0 {
+		return false, nil
+	}
+
+	migrationBoundariesMu.RLock()
+	defer migrationBoundariesMu.RUnlock()
+	var crossed []string
+	for boundary := range migrationBoundaries {
+		ver, err := parseSemver(boundary)
+		if err != nil {
+			continue
+		}
+		if ver.compare(toVer) > 0 && ver.compare(fromVer) <= 0 {
+			crossed = append(crossed, boundary)
+		}
+	}
+	sort.Slice(crossed, func(i, j int) bool {
+		a, _ := parseSemver(crossed[i])
+		b, _ := parseSemver(crossed[j])
+		return a.compare(b) > 0
+	})
+	return len(crossed) > 0, crossed
+}
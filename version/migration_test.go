@@ -0,0 +1,58 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCrossesMigrationBoundary(t *testing.T) {
+	defer func() {
+		migrationBoundariesMu.Lock()
+		defer migrationBoundariesMu.Unlock()
+		migrationBoundaries = make(map[string]bool)
+	}()
+	RegisterMigrationBoundary("1.10.0")
+	RegisterMigrationBoundary("1.12.0")
+	RegisterMigrationBoundary("latest")
+
+	cases := []struct {
+		from string
+		to   string
+		want []string
+	}{
+		{"1.12.3", "1.9.0", []string{"1.12.0", "1.10.0"}},
+		{"1.12.0", "1.11.5", []string{"1.12.0"}},
+		{"1.11.5", "1.10.0", nil},
+		{"1.11.5", "1.9.9", []string{"1.10.0"}},
+		{"1.9.0", "1.12.3", nil},
+		{"1.12.0", "1.12.0", nil},
+		{"unknown", "1.9.0", nil},
+		{"1.12.0", "unknown", nil},
+	}
+
+	for _, v := range cases {
+		t.Run(v.from+" to "+v.to, func(t *testing.T) {
+			crosses, got := CrossesMigrationBoundary(v.from, v.to)
+			if crosses != (len(v.want) > 0) {
+				t.Errorf("got %v; want %v", crosses, len(v.want) > 0)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
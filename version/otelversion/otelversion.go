@@ -0,0 +1,45 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otelversion reports build information as OpenTelemetry attributes. It is kept apart
+// from the version package so that binaries not using OpenTelemetry do not depend on it.
+package otelversion
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/semconv"
+
+	"istio.io/pkg/version"
+)
+
+// OpenTelemetry attribute keys for build fields that have no semantic-convention equivalent.
+const (
+	RevisionKey      = attribute.Key("istio.build.revision")
+	GolangVersionKey = attribute.Key("istio.build.golang_version")
+	StatusKey        = attribute.Key("istio.build.status")
+	TagKey           = attribute.Key("istio.build.tag")
+)
+
+// Attributes returns the build information as OpenTelemetry resource attributes.
+// The version is reported under the semantic-convention key `service.version`; the other
+// fields use the `istio.build.*` keys above.
+func Attributes(b version.BuildInfo) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.ServiceVersionKey.String(b.Version),
+		RevisionKey.String(b.GitRevision),
+		GolangVersionKey.String(b.GolangVersion),
+		StatusKey.String(b.BuildStatus),
+		TagKey.String(b.GitTag),
+	}
+}
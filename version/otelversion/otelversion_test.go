@@ -0,0 +1,42 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelversion
+
+import (
+	"testing"
+
+	"istio.io/pkg/version"
+)
+
+func TestAttributes(t *testing.T) {
+	in := version.BuildInfo{Version: "VER", GitRevision: "GITREV", GolangVersion: "GOLANGVER", BuildStatus: "STATUS", GitTag: "TAG"}
+	want := map[string]string{
+		"service.version":            "VER",
+		"istio.build.revision":       "GITREV",
+		"istio.build.golang_version": "GOLANGVER",
+		"istio.build.status":         "STATUS",
+		"istio.build.tag":            "TAG",
+	}
+
+	got := Attributes(in)
+	if len(got) != len(want) {
+		t.Fatalf("got %d attributes; want %d", len(got), len(want))
+	}
+	for _, kv := range got {
+		if w, ok := want[string(kv.Key)]; !ok || kv.Value.AsString() != w {
+			t.Errorf("got %s=%q; want %q", kv.Key, kv.Value.AsString(), w)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"fmt"
+)
+
+// VersionPolicy describes the versions an organization permits. It should be created with
+// NewVersionPolicy, which validates the bounds.
+type VersionPolicy struct {
+	// Min is the lowest permitted version, inclusive. Empty means no lower bound.
+	Min string
+	// Max is the highest permitted version, inclusive. Empty means no upper bound.
+	Max string
+	// AllowPrerelease permits pre-release versions within the bounds.
+	AllowPrerelease bool
+}
+
+// NewVersionPolicy creates a VersionPolicy, returning an error if a bound is not a valid
+// version or if min is greater than max.
+func NewVersionPolicy(min, max string, allowPrerelease bool) (VersionPolicy, error) {
+	p := VersionPolicy{Min: min, Max: max, AllowPrerelease: allowPrerelease}
+	lo, hi, err := p.bounds()
+	if err != nil {
+		return VersionPolicy{}, err
+	}
+	if lo != nil && hi != nil && lo.compare(*hi) > 0 {
+		return VersionPolicy{}, fmt.Errorf("invalid version policy: minimum %s is greater than maximum %s", min, max)
+	}
+	return p, nil
+}
+
+// bounds parses the policy bounds, returning nil for unset ones.
+func (p VersionPolicy) bounds() (*semver, *semver, error) {
+	var lo, hi *semver
+	if p.Min != "" {
+		ver, err := parseSemver(p.Min)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid version policy minimum: %v", err)
+		}
+		lo = &ver
+	}
+	if p.Max != "" {
+		ver, err := parseSemver(p.Max)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid version policy maximum: %v", err)
+		}
+		hi = &ver
+	}
+	return lo, hi, nil
+}
+
+// Permits reports whether the build's version satisfies the policy, comparing versions
+// ignoring build metadata. When it does not, the returned string names the violated bound.
+func (p VersionPolicy) Permits(b BuildInfo) (bool, string) {
+	lo, hi, err := p.bounds()
+	if err != nil {
+		return false, err.Error()
+	}
+	ver, err := parseSemver(b.Version)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	if !p.AllowPrerelease && ver.prerelease != "" {
+		return false, fmt.Sprintf("version %s is a pre-release, which the policy does not permit", b.Version)
+	}
+	if lo != nil && ver.compare(*lo) < 0 {
+		return false, fmt.Sprintf("version %s is below the minimum %s", b.Version, p.Min)
+	}
+	if hi != nil && ver.compare(*hi) > 0 {
+		return false, fmt.Sprintf("version %s is above the maximum %s", b.Version, p.Max)
+	}
+	return true, ""
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewVersionPolicy(t *testing.T) {
+	cases := []struct {
+		name       string
+		min        string
+		max        string
+		expectFail bool
+	}{
+		{"bounded", "1.10.0", "1.12.0", false},
+		{"single version", "1.11.2", "v1.11.2", false},
+		{"unbounded", "", "", false},
+		{"min only", "1.10.0", "", false},
+		{"inverted", "1.12.0", "1.10.0", true},
+		{"bad min", "latest", "1.12.0", true},
+		{"bad max", "1.10.0", "next", true},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			p, err := NewVersionPolicy(v.min, v.max, false)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail {
+				if err != nil {
+					t.Errorf("Got %v, expected success", err)
+				}
+				if p.Min != v.min || p.Max != v.max {
+					t.Errorf("got %+v; want bounds %q and %q", p, v.min, v.max)
+				}
+			}
+		})
+	}
+}
+
+func TestVersionPolicyPermits(t *testing.T) {
+	strict := VersionPolicy{Min: "1.10.0", Max: "1.12.0"}
+	lenient := VersionPolicy{Min: "1.10.0", Max: "1.12.0", AllowPrerelease: true}
+
+	cases := []struct {
+		name    string
+		policy  VersionPolicy
+		version string
+		want    bool
+		reason  string
+	}{
+		{"within", strict, "1.11.2", true, ""},
+		{"at minimum", strict, "1.10.0+build.1", true, ""},
+		{"at maximum", strict, "v1.12.0", true, ""},
+		{"below", strict, "1.9.9", false, "below the minimum 1.10.0"},
+		{"above", strict, "1.12.1", false, "above the maximum 1.12.0"},
+		{"prerelease denied", strict, "1.11.0-rc.1", false, "pre-release"},
+		{"prerelease allowed", lenient, "1.11.0-rc.1", true, ""},
+		{"prerelease of maximum", lenient, "1.12.0-rc.1", true, ""},
+		{"prerelease above", lenient, "1.13.0-alpha.1", false, "above the maximum"},
+		{"unbounded", VersionPolicy{}, "100.0.0", true, ""},
+		{"unparseable", strict, "unknown", false, "unknown"},
+		{"invalid policy", VersionPolicy{Min: "latest"}, "1.11.2", false, "invalid version policy minimum"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, reason := v.policy.Permits(BuildInfo{Version: v.version})
+			if got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+			if !strings.Contains(reason, v.reason) || (v.reason == "") != (reason == "") {
+				t.Errorf("got reason %q; want it to contain %q", reason, v.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Field numbers of the protobuf encoding of BuildInfo, matching this message definition:
+//
+//	message BuildInfo {
+//	  string version = 1;
+//	  string revision = 2;
+//	  string golang_version = 3;
+//	  string status = 4;
+//	  string tag = 5;
+//	  map<string, string> extra = 6;
+//	  string source = 7;
+//	}
+//
+// These numbers are part of the wire format and must never change.
+const (
+	protoVersionField       = 1
+	protoRevisionField      = 2
+	protoGolangVersionField = 3
+	protoStatusField        = 4
+	protoTagField           = 5
+	protoExtraField         = 6
+	protoSourceField        = 7
+
+	// map entries are encoded as messages with the key and value in these fields
+	protoMapKeyField   = 1
+	protoMapValueField = 2
+)
+
+// Protobuf wire types.
+const (
+	protoVarint          = 0
+	protoFixed64         = 1
+	protoLengthDelimited = 2
+	protoFixed32         = 5
+)
+
+// ProtoBytes encodes the build information in the protobuf wire format, following the
+// message definition documented with the field numbers above. As in proto3, empty fields are
+// omitted. Extra entries are written sorted by key, so the encoding is deterministic.
+func (b BuildInfo) ProtoBytes() []byte {
+	var data []byte
+	for _, f := range b.protoFields() {
+		if *f.value != "" {
+			data = appendProtoString(data, f.num, *f.value)
+		}
+	}
+
+	keys := make([]string, 0, len(b.Extra))
+	for k := range b.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = appendProtoString(entry, protoMapKeyField, k)
+		entry = appendProtoString(entry, protoMapValueField, b.Extra[k])
+		data = appendProtoBytes(data, protoExtraField, entry)
+	}
+	return data
+}
+
+// BuildInfoFromProto decodes build information encoded by ProtoBytes, or by any protobuf
+// implementation of the documented message. Unknown fields are skipped.
+func BuildInfoFromProto(data []byte) (BuildInfo, error) {
+	res := BuildInfo{}
+	fields := make(map[int]*string)
+	for _, f := range res.protoFields() {
+		fields[f.num] = f.value
+	}
+
+	for len(data) > 0 {
+		num, wireType, value, rest, err := readProtoField(data)
+		if err != nil {
+			return BuildInfo{}, fmt.Errorf("invalid BuildInfo message: %v", err)
+		}
+		data = rest
+
+		if wireType != protoLengthDelimited {
+			continue
+		}
+		if f, ok := fields[num]; ok {
+			*f = string(value)
+		} else if num == protoExtraField {
+			k, v, err := readProtoMapEntry(value)
+			if err != nil {
+				return BuildInfo{}, fmt.Errorf("invalid BuildInfo message, extra entry: %v", err)
+			}
+			if res.Extra == nil {
+				res.Extra = make(map[string]string)
+			}
+			res.Extra[k] = v
+		}
+	}
+	return res, nil
+}
+
+type protoField struct {
+	num   int
+	value *string
+}
+
+// protoFields returns the string fields of the message with their field numbers.
+func (b *BuildInfo) protoFields() []protoField {
+	return []protoField{
+		{protoVersionField, &b.Version},
+		{protoRevisionField, &b.GitRevision},
+		{protoGolangVersionField, &b.GolangVersion},
+		{protoStatusField, &b.BuildStatus},
+		{protoTagField, &b.GitTag},
+		{protoSourceField, &b.Source},
+	}
+}
+
+func readProtoMapEntry(data []byte) (string, string, error) {
+	var k, v string
+	for len(data) > 0 {
+		num, wireType, value, rest, err := readProtoField(data)
+		if err != nil {
+			return "", "", err
+		}
+		data = rest
+		if wireType != protoLengthDelimited {
+			continue
+		}
+		switch num {
+		case protoMapKeyField:
+			k = string(value)
+		case protoMapValueField:
+			v = string(value)
+		}
+	}
+	return k, v, nil
+}
+
+func appendProtoVarint(data []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(data, buf[:n]...)
+}
+
+func appendProtoBytes(data []byte, num int, value []byte) []byte {
+	data = appendProtoVarint(data, uint64(num)<<3|protoLengthDelimited)
+	data = appendProtoVarint(data, uint64(len(value)))
+	return append(data, value...)
+}
+
+func appendProtoString(data []byte, num int, value string) []byte {
+	return appendProtoBytes(data, num, []byte(value))
+}
+
+// readProtoField reads one field, returning its number, wire type, the payload of
+// length-delimited fields, and the remaining data.
+func readProtoField(data []byte) (int, int, []byte, []byte, error) {
+	key, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, 0, nil, nil, fmt.Errorf("malformed field key")
+	}
+	data = data[n:]
+	num, wireType := int(key>>3), int(key&7)
+	if num == 0 {
+		return 0, 0, nil, nil, fmt.Errorf("invalid field number 0")
+	}
+
+	switch wireType {
+	case protoVarint:
+		if _, n = binary.Uvarint(data); n <= 0 {
+			return 0, 0, nil, nil, fmt.Errorf("field %d: malformed varint", num)
+		}
+		return num, wireType, nil, data[n:], nil
+	case protoFixed64, protoFixed32:
+		size := 8
+		if wireType == protoFixed32 {
+			size = 4
+		}
+		if len(data) < size {
+			return 0, 0, nil, nil, fmt.Errorf("field %d: needs %d bytes but only %d remain", num, size, len(data))
+		}
+		return num, wireType, nil, data[size:], nil
+	case protoLengthDelimited:
+		l, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, 0, nil, nil, fmt.Errorf("field %d: malformed length", num)
+		}
+		data = data[n:]
+		if uint64(len(data)) < l {
+			return 0, 0, nil, nil, fmt.Errorf("field %d: needs %d bytes but only %d remain", num, l, len(data))
+		}
+		return num, wireType, data[:l], data[l:], nil
+	}
+	return 0, 0, nil, nil, fmt.Errorf("field %d: unsupported wire type %d", num, wireType)
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		in   BuildInfo
+	}{
+		{"empty", BuildInfo{}},
+		{"init", Info},
+		{
+			"all specified",
+			BuildInfo{
+				Version:       "1.11.2",
+				GitRevision:   "3a136c90ec5e308f236e0d7ebb5c4c5e405217f4",
+				GolangVersion: "go1.16.5",
+				BuildStatus:   "Clean",
+				GitTag:        "1.11.2",
+			},
+		},
+		{"long field", BuildInfo{Version: strings.Repeat("v", 300)}},
+		{"extra", BuildInfo{Version: "1.11.2", Extra: map[string]string{"pipeline": "1234", "ticket": "ABC-1", "empty": ""}}},
+		{"source", BuildInfo{Version: "1.11.2", Source: "ci", Extra: map[string]string{"pipeline": "1234"}}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := BuildInfoFromProto(v.in.ProtoBytes())
+			if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			if !reflect.DeepEqual(got, v.in) {
+				t.Errorf("got %#v; want %#v", got, v.in)
+			}
+		})
+	}
+}
+
+func TestProtoBytes(t *testing.T) {
+	in := BuildInfo{Version: "1.2", BuildStatus: "Clean", Extra: map[string]string{"b": "2", "a": "1"}}
+	want := []byte{
+		0x0a, 3, '1', '.', '2', // version
+		0x22, 5, 'C', 'l', 'e', 'a', 'n', // status
+		0x32, 6, 0x0a, 1, 'a', 0x12, 1, '1', // extra a=1
+		0x32, 6, 0x0a, 1, 'b', 0x12, 1, '2', // extra b=2
+	}
+	if got := in.ProtoBytes(); !bytes.Equal(got, want) {
+		t.Errorf("got % x; want % x", got, want)
+	}
+}
+
+func TestBuildInfoFromProto(t *testing.T) {
+	cases := []struct {
+		name       string
+		in         []byte
+		expectFail bool
+		want       BuildInfo
+	}{
+		{
+			"unknown fields skipped",
+			[]byte{
+				0x08, 0x96, 0x01, // field 1 as varint
+				0x0a, 1, '1', // version
+				0x39, 1, 2, 3, 4, 5, 6, 7, 8, // field 7 fixed64
+				0x45, 1, 2, 3, 4, // field 8 fixed32
+				0x4a, 2, 'x', 'y', // field 9 bytes
+			},
+			false,
+			BuildInfo{Version: "1"},
+		},
+		{"last value wins", []byte{0x0a, 1, '1', 0x0a, 1, '2'}, false, BuildInfo{Version: "2"}},
+		{"map entry without value", []byte{0x32, 3, 0x0a, 1, 'a'}, false, BuildInfo{Extra: map[string]string{"a": ""}}},
+		{"truncated", []byte{0x0a, 5, '1'}, true, BuildInfo{}},
+		{"malformed key", []byte{0x80}, true, BuildInfo{}},
+		{"field zero", []byte{0x02, 0}, true, BuildInfo{}},
+		{"group wire type", []byte{0x0b}, true, BuildInfo{}},
+		{"bad map entry", []byte{0x32, 2, 0x0a, 5}, true, BuildInfo{}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := BuildInfoFromProto(v.in)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %#v; want %#v", got, v.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"sync"
+)
+
+// Provider supplies the build information of the running binary.
+type Provider interface {
+	BuildInfo() BuildInfo
+}
+
+// ldflagsProvider provides the build information populated at build time using -ldflags -X,
+// as modified by Set and SetExtra.
+type ldflagsProvider struct{}
+
+func (ldflagsProvider) BuildInfo() BuildInfo {
+	mu.RLock()
+	defer mu.RUnlock()
+	return Info
+}
+
+var (
+	// mu guards provider, as well as Info when modified through Set and SetExtra.
+	mu       sync.RWMutex
+	provider Provider = ldflagsProvider{}
+)
+
+// SetProvider registers the provider consulted by Get and the version command. Passing nil
+// restores the default provider, which reports the ldflags-based Info.
+//
+// SetProvider is safe for concurrent use with Get. The provider itself must be safe for
+// concurrent use if Get is called from several goroutines.
+func SetProvider(p Provider) {
+	if p == nil {
+		p = ldflagsProvider{}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	provider = p
+}
+
+// Get returns the build information reported by the registered provider. It is safe for
+// concurrent use with Set, SetExtra and SetProvider.
+//
+// Reading the exported Info variable directly is not race-safe once the build information
+// is modified at runtime; such callers should migrate to Get.
+func Get() BuildInfo {
+	mu.RLock()
+	p := provider
+	mu.RUnlock()
+	return p.BuildInfo()
+}
+
+// Set replaces the build information reported by the default provider. It is safe for
+// concurrent use with Get.
+func Set(b BuildInfo) {
+	mu.Lock()
+	defer mu.Unlock()
+	Info = b
+}
+
+// Snapshot captures the current Info, DockerInfo and provider, returning a function that
+// restores them. It is meant for tests that modify the build information:
+//
+//	defer version.Snapshot()()
+func Snapshot() func() {
+	mu.RLock()
+	info, dockerInfo, p := Info, DockerInfo, provider
+	mu.RUnlock()
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		Info, DockerInfo, provider = info, dockerInfo, p
+	}
+}
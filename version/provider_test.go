@@ -0,0 +1,103 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type fixedProvider BuildInfo
+
+func (p fixedProvider) BuildInfo() BuildInfo {
+	return BuildInfo(p)
+}
+
+func TestSetProvider(t *testing.T) {
+	defer Snapshot()()
+
+	if got := Get(); !reflect.DeepEqual(got, Info) {
+		t.Errorf("got %v; want default %v", got, Info)
+	}
+
+	want := BuildInfo{Version: "1.11.2", GitRevision: "abc123", BuildStatus: "Clean"}
+	SetProvider(fixedProvider(want))
+	if got := Get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+
+	cmd := CobraCommand()
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	cmd.SetArgs([]string{"version", "--short"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Got %v, expecting success", err)
+	}
+	if out.String() != "1.11.2\n" {
+		t.Errorf("got %q; want %q", out.String(), "1.11.2\n")
+	}
+
+	SetProvider(nil)
+	if got := Get(); !reflect.DeepEqual(got, Info) {
+		t.Errorf("got %v; want default %v", got, Info)
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	defer Snapshot()()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b := Get()
+				_ = b.LongForm()
+			}
+		}()
+	}
+
+	Set(BuildInfo{Version: "1.11.2"})
+	SetExtra("pipeline", "1234")
+	SetProvider(fixedProvider(BuildInfo{Version: "1.12.0"}))
+	SetProvider(nil)
+	wg.Wait()
+
+	got := Get()
+	if got.Version != "1.11.2" || got.Extra["pipeline"] != "1234" {
+		t.Errorf("got %v; want version 1.11.2 with pipeline extra", got)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	info, dockerInfo := Get(), DockerInfo
+
+	restore := Snapshot()
+	Set(BuildInfo{Version: "1.11.2"})
+	SetExtra("pipeline", "1234")
+	DockerInfo = DockerBuildInfo{Hub: "gcr.io/istio", Tag: "1.11.2"}
+	SetProvider(fixedProvider(BuildInfo{Version: "1.12.0"}))
+	restore()
+
+	if got := Get(); !reflect.DeepEqual(got, info) {
+		t.Errorf("got %v; want %v", got, info)
+	}
+	if DockerInfo != dockerInfo {
+		t.Errorf("got %v; want %v", DockerInfo, dockerInfo)
+	}
+}
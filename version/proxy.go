@@ -0,0 +1,276 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProxyVersionAnnotation is the workload annotation recording the version of the injected sidecar.
+const ProxyVersionAnnotation = "sidecar.istio.io/version"
+
+// imageTagSuffixes are image variant suffixes appended to the version in Istio image tags.
+var imageTagSuffixes = []string{"-distroless", "-debug"}
+
+// NewProxyInfo creates a ProxyInfo from a raw Envoy-style version string, such as
+// `<commit>/1.11.2/Clean/RELEASE/BoringSSL`. Only the Istio version portion is kept,
+// without any build metadata. If no recognizable version is found, IstioVersion is "unknown".
+func NewProxyInfo(id, raw string) ProxyInfo {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == '/' || r == ',' || r == ';' || r == ' ' || r == '\t'
+	})
+	for _, field := range fields {
+		if ver, ok := cleanVersion(field); ok {
+			return ProxyInfo{ID: id, IstioVersion: ver}
+		}
+	}
+
+	return ProxyInfo{ID: id, IstioVersion: "unknown"}
+}
+
+// ArchMismatch groups proxy IDs by architecture, so that mixed-architecture
+// fleets can be spotted. Proxies that do not report an architecture are
+// grouped under "unknown".
+func ArchMismatch(proxies []ProxyInfo) map[string][]string {
+	res := make(map[string][]string)
+	for _, proxy := range proxies {
+		arch := proxy.Arch
+		if arch == "" {
+			arch = "unknown"
+		}
+		res[arch] = append(res[arch], proxy.ID)
+	}
+	return res
+}
+
+// ReconcileReport partitions proxies by how their version relates to the control plane.
+type ReconcileReport struct {
+	// Expected is the version proxies are compared against: the highest control plane
+	// component version. It is empty if no component reports a parseable version.
+	Expected string
+	Ahead    []ProxyInfo
+	Behind   []ProxyInfo
+	Matching []ProxyInfo
+	// Unknown holds proxies whose version cannot be parsed, or all proxies when
+	// there is no expected version.
+	Unknown []ProxyInfo
+}
+
+// ReconcileProxies compares each proxy against the control plane. The expected proxy version
+// is the highest component version in mesh, since after an upgrade proxies are meant to catch
+// up with the newest control plane. Versions are compared ignoring build metadata.
+func ReconcileProxies(mesh MeshInfo, proxies []ProxyInfo) ReconcileReport {
+	expectedRaw, expected, ok := mesh.maxVersion()
+
+	res := ReconcileReport{Expected: expectedRaw}
+	for _, proxy := range proxies {
+		ver, err := parseSemver(proxy.IstioVersion)
+		if !ok || err != nil {
+			res.Unknown = append(res.Unknown, proxy)
+			continue
+		}
+		switch ver.compare(expected) {
+		case -1:
+			res.Behind = append(res.Behind, proxy)
+		case 1:
+			res.Ahead = append(res.Ahead, proxy)
+		default:
+			res.Matching = append(res.Matching, proxy)
+		}
+	}
+	return res
+}
+
+// VersionFromImageTag extracts the Istio version from an image reference or tag, such as
+// `docker.io/istio/proxyv2:1.11.2-distroless`. Known variant suffixes (-distroless, -debug)
+// and any digest are removed.
+func VersionFromImageTag(tag string) (string, error) {
+	ref := strings.TrimSpace(tag)
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	// only a colon after the last slash separates the tag; earlier ones belong to a registry port
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[i+1:]
+	}
+	for _, suffix := range imageTagSuffixes {
+		ref = strings.TrimSuffix(ref, suffix)
+	}
+
+	ver, ok := cleanVersion(ref)
+	if !ok {
+		return "", fmt.Errorf("unrecognized image tag %q", tag)
+	}
+	return ver, nil
+}
+
+// ProxyNeedsRestart reports whether a proxy runs an older release train (major.minor) than
+// the control plane, indicating its workload should be restarted to pick up a new sidecar.
+// Patch differences are ignored. Unparseable versions conservatively require a restart.
+func ProxyNeedsRestart(proxy ProxyInfo, controlPlane BuildInfo) bool {
+	p, err := parseSemver(proxy.IstioVersion)
+	if err != nil {
+		return true
+	}
+	cp, err := parseSemver(controlPlane.Version)
+	if err != nil {
+		return true
+	}
+	if p.major != cp.major {
+		return p.major < cp.major
+	}
+	return p.minor < cp.minor
+}
+
+// VersionFromAnnotations returns the proxy version recorded in the ProxyVersionAnnotation
+// ("sidecar.istio.io/version") workload annotation. It returns false when the annotation
+// is missing or blank.
+func VersionFromAnnotations(annotations map[string]string) (string, bool) {
+	ver := strings.TrimSpace(annotations[ProxyVersionAnnotation])
+	if ver == "" {
+		return "", false
+	}
+	return ver, true
+}
+
+// ProxiesInRange returns the proxies whose version lies within [min, max] inclusive, compared
+// ignoring build metadata. Proxies with unparseable versions are left out; an unparseable min
+// or max, or a min greater than max, is an error.
+func ProxiesInRange(proxies []ProxyInfo, min, max string) ([]ProxyInfo, error) {
+	lo, err := parseSemver(min)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := parseSemver(max)
+	if err != nil {
+		return nil, err
+	}
+	if lo.compare(hi) > 0 {
+		return nil, fmt.Errorf("invalid version range: %s is greater than %s", min, max)
+	}
+
+	var res []ProxyInfo
+	for _, proxy := range proxies {
+		ver, err := parseSemver(proxy.IstioVersion)
+		if err != nil {
+			continue
+		}
+		if ver.compare(lo) >= 0 && ver.compare(hi) <= 0 {
+			res = append(res, proxy)
+		}
+	}
+	return res, nil
+}
+
+// ProxyClassification partitions proxies by compatibility with a control plane.
+type ProxyClassification struct {
+	// Compatible holds proxies on the control plane's release train (major.minor).
+	Compatible []ProxyInfo
+	// Behind holds proxies on an older release train.
+	Behind []ProxyInfo
+	// Ahead holds proxies on a newer release train.
+	Ahead []ProxyInfo
+	// Unknown holds proxies whose version cannot be parsed, or all proxies when the
+	// control plane version cannot be parsed.
+	Unknown []ProxyInfo
+}
+
+// ClassifyProxies partitions proxies by their release train relative to the control plane.
+// Unlike ReconcileProxies, patch and pre-release differences are considered compatible.
+func ClassifyProxies(controlPlane BuildInfo, proxies []ProxyInfo) ProxyClassification {
+	var res ProxyClassification
+	cp, cpErr := parseSemver(controlPlane.Version)
+	for _, proxy := range proxies {
+		ver, err := parseSemver(proxy.IstioVersion)
+		if cpErr != nil || err != nil {
+			res.Unknown = append(res.Unknown, proxy)
+			continue
+		}
+		c := compareInt(ver.major, cp.major)
+		if c == 0 {
+			c = compareInt(ver.minor, cp.minor)
+		}
+		switch c {
+		case -1:
+			res.Behind = append(res.Behind, proxy)
+		case 1:
+			res.Ahead = append(res.Ahead, proxy)
+		default:
+			res.Compatible = append(res.Compatible, proxy)
+		}
+	}
+	return res
+}
+
+// ParseProxyStatus extracts proxy IDs and Istio versions from the tabular text printed by
+// `istioctl proxy-status`, whose first column is the proxy ID and whose last column is its
+// version. Columns may be separated by any amount of whitespace. Header rows, and rows
+// without a recognizable version, are skipped. An error is only returned when the output
+// has rows other than headers but no proxy could be parsed from them; output with no
+// proxies, made of the header alone, yields no proxies and no error.
+func ParseProxyStatus(output string) ([]ProxyInfo, error) {
+	var (
+		res     []ProxyInfo
+		content bool
+	)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == "NAME" {
+			continue
+		}
+		content = true
+		if len(fields) < 2 {
+			continue
+		}
+		if proxy := NewProxyInfo(fields[0], fields[len(fields)-1]); proxy.IstioVersion != "unknown" {
+			res = append(res, proxy)
+		}
+	}
+	if content && len(res) == 0 {
+		return nil, fmt.Errorf("no proxy versions found in proxy-status output")
+	}
+	return res, nil
+}
+
+// CompatibleControlPlanes returns the control planes the proxy may safely connect to, in
+// their original order: those within one minor version of the proxy, as rated by
+// CompatibilityScore. Control planes with unparseable versions are never compatible.
+func CompatibleControlPlanes(proxy ProxyInfo, controlPlanes []BuildInfo) []BuildInfo {
+	proxyBuild := BuildInfo{Version: proxy.IstioVersion}
+	res := []BuildInfo{}
+	for _, cp := range controlPlanes {
+		if CompatibilityScore(proxyBuild, cp) >= 50 {
+			res = append(res, cp)
+		}
+	}
+	return res
+}
+
+// AllProxiesAtVersion reports whether every proxy runs the target version, compared ignoring
+// build metadata, along with the proxies that do not. Proxies with unparseable versions are
+// counted as not at the target, as are all proxies when the target is unparseable. The list
+// of stragglers is empty, but not nil, when all proxies are at the target.
+func AllProxiesAtVersion(proxies []ProxyInfo, target string) (bool, []ProxyInfo) {
+	want, werr := parseSemver(target)
+	stragglers := []ProxyInfo{}
+	for _, proxy := range proxies {
+		ver, err := parseSemver(proxy.IstioVersion)
+		if werr != nil || err != nil || ver.compare(want) != 0 {
+			stragglers = append(stragglers, proxy)
+		}
+	}
+	return len(stragglers) == 0, stragglers
+}
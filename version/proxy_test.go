@@ -0,0 +1,387 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewProxyInfo(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"plain", "1.11.2", "1.11.2"},
+		{"envoy", "3c0a9a4c3b6e1ad3a26c4b0e39dc3bd6e0f1e0c8/1.11.2/Clean/RELEASE/BoringSSL", "1.11.2"},
+		{"prerelease", "3c0a9a4c3b6e1ad3a26c4b0e39dc3bd6e0f1e0c8/1.12.0-rc.1/Modified/DEBUG/BoringSSL", "1.12.0-rc.1"},
+		{"metadata", "v1.11.2+build.2021-09-01", "1.11.2"},
+		{"build date", "istio-proxy 2021-09-01 1.10.4", "1.10.4"},
+		{"no version", "3c0a9a4c3b6e1ad3a26c4b0e39dc3bd6e0f1e0c8/Clean", "unknown"},
+		{"empty", "", "unknown"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got := NewProxyInfo("pod.ns", v.raw)
+			if got.ID != "pod.ns" {
+				t.Errorf("got ID %q; want %q", got.ID, "pod.ns")
+			}
+			if got.IstioVersion != v.want {
+				t.Errorf("got %q; want %q", got.IstioVersion, v.want)
+			}
+		})
+	}
+}
+
+func TestArchMismatch(t *testing.T) {
+	cases := []struct {
+		name    string
+		proxies []ProxyInfo
+		want    map[string][]string
+	}{
+		{"none", nil, map[string][]string{}},
+		{
+			"mixed",
+			[]ProxyInfo{
+				{ID: "a", IstioVersion: "1.11.2", Arch: "amd64"},
+				{ID: "b", IstioVersion: "1.11.2", Arch: "arm64"},
+				{ID: "c", IstioVersion: "1.11.2", Arch: "amd64"},
+				{ID: "d", IstioVersion: "1.11.2"},
+			},
+			map[string][]string{"amd64": {"a", "c"}, "arm64": {"b"}, "unknown": {"d"}},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := ArchMismatch(v.proxies); !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestReconcileProxies(t *testing.T) {
+	proxies := []ProxyInfo{
+		{ID: "old", IstioVersion: "1.0.1"},
+		{ID: "same", IstioVersion: "1.2.0"},
+		{ID: "new", IstioVersion: "1.3.0"},
+		{ID: "bad", IstioVersion: "unknown"},
+		{ID: "meta", IstioVersion: "1.2.0+build.1"},
+	}
+
+	cases := []struct {
+		name string
+		mesh MeshInfo
+		want ReconcileReport
+	}{
+		{
+			"multi version mesh",
+			meshInfoMultiVersion,
+			ReconcileReport{
+				Expected: "1.2",
+				Ahead:    []ProxyInfo{proxies[2]},
+				Behind:   []ProxyInfo{proxies[0]},
+				Matching: []ProxyInfo{proxies[1], proxies[4]},
+				Unknown:  []ProxyInfo{proxies[3]},
+			},
+		},
+		{
+			"empty mesh",
+			meshEmptyVersion,
+			ReconcileReport{Unknown: proxies},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := ReconcileProxies(v.mesh, proxies); !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %+v; want %+v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestVersionFromImageTag(t *testing.T) {
+	cases := []struct {
+		tag        string
+		expectFail bool
+		want       string
+	}{
+		{"1.11.2", false, "1.11.2"},
+		{"proxyv2:1.11.2-distroless", false, "1.11.2"},
+		{"docker.io/istio/proxyv2:1.11.2-debug", false, "1.11.2"},
+		{"localhost:5000/istio/proxyv2:1.12.0-rc.1", false, "1.12.0-rc.1"},
+		{"gcr.io/istio-release/proxyv2:1.11.2@sha256:0123456789abcdef", false, "1.11.2"},
+		{"proxyv2:latest", true, ""},
+		{"localhost:5000/istio/proxyv2", true, ""},
+		{"", true, ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.tag, func(t *testing.T) {
+			got, err := VersionFromImageTag(v.tag)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestProxyNeedsRestart(t *testing.T) {
+	cases := []struct {
+		proxy        string
+		controlPlane string
+		want         bool
+	}{
+		{"1.11.2", "1.12.0", true},
+		{"1.11.2", "2.0.0", true},
+		{"1.12.0", "1.12.3", false},
+		{"1.12.0", "1.12.0", false},
+		{"1.13.0", "1.12.0", false},
+		{"2.0.0", "1.12.0", false},
+		{"unknown", "1.12.0", true},
+		{"1.12.0", "unknown", true},
+	}
+
+	for _, v := range cases {
+		t.Run(v.proxy+" vs "+v.controlPlane, func(t *testing.T) {
+			got := ProxyNeedsRestart(ProxyInfo{ID: "pod.ns", IstioVersion: v.proxy}, BuildInfo{Version: v.controlPlane})
+			if got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestVersionFromAnnotations(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		wantOK      bool
+		want        string
+	}{
+		{"present", map[string]string{"sidecar.istio.io/version": "1.11.2"}, true, "1.11.2"},
+		{"padded", map[string]string{"sidecar.istio.io/version": " 1.11.2 "}, true, "1.11.2"},
+		{"blank", map[string]string{"sidecar.istio.io/version": ""}, false, ""},
+		{"other keys", map[string]string{"sidecar.istio.io/status": "{}"}, false, ""},
+		{"nil", nil, false, ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, ok := VersionFromAnnotations(v.annotations)
+			if ok != v.wantOK || got != v.want {
+				t.Errorf("got %q, %v; want %q, %v", got, ok, v.want, v.wantOK)
+			}
+		})
+	}
+}
+
+func TestProxiesInRange(t *testing.T) {
+	proxies := []ProxyInfo{
+		{ID: "a", IstioVersion: "1.8.6"},
+		{ID: "b", IstioVersion: "1.9.0"},
+		{ID: "c", IstioVersion: "1.10.0-rc.1"},
+		{ID: "d", IstioVersion: "1.10.5+build.1"},
+		{ID: "e", IstioVersion: "1.10.6"},
+		{ID: "f", IstioVersion: "unknown"},
+	}
+
+	cases := []struct {
+		name       string
+		min        string
+		max        string
+		expectFail bool
+		want       []ProxyInfo
+	}{
+		{"inclusive", "1.9.0", "1.10.5", false, proxies[1:4]},
+		{"single version", "1.10.6", "1.10.6", false, proxies[4:5]},
+		{"none", "2.0.0", "2.1.0", false, nil},
+		{"bad min", "latest", "1.10.5", true, nil},
+		{"bad max", "1.9.0", "", true, nil},
+		{"inverted", "1.10.5", "1.9.0", true, nil},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := ProxiesInRange(proxies, v.min, v.max)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestClassifyProxies(t *testing.T) {
+	proxies := []ProxyInfo{
+		{ID: "old-major", IstioVersion: "0.8.0"},
+		{ID: "old-minor", IstioVersion: "1.10.5"},
+		{ID: "old-patch", IstioVersion: "1.11.0"},
+		{ID: "same", IstioVersion: "1.11.2"},
+		{ID: "new-patch", IstioVersion: "1.11.3-rc.1"},
+		{ID: "new-minor", IstioVersion: "1.12.0"},
+		{ID: "new-major", IstioVersion: "2.0.0"},
+		{ID: "bad", IstioVersion: "unknown"},
+	}
+
+	cases := []struct {
+		name         string
+		controlPlane string
+		want         ProxyClassification
+	}{
+		{
+			"release",
+			"1.11.2",
+			ProxyClassification{
+				Compatible: proxies[2:5],
+				Behind:     proxies[0:2],
+				Ahead:      proxies[5:7],
+				Unknown:    proxies[7:],
+			},
+		},
+		{"unparseable control plane", "unknown", ProxyClassification{Unknown: proxies}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := ClassifyProxies(BuildInfo{Version: v.controlPlane}, proxies); !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %+v; want %+v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestParseProxyStatus(t *testing.T) {
+	output := `NAME                                                  CDS        LDS        EDS        RDS          ISTIOD                      VERSION
+details-v1-5498c86cf5-vnxw5.default                   SYNCED     SYNCED     SYNCED     SYNCED       istiod-7f8b586864-mv7ht     1.11.2
+istio-ingressgateway-5d9b5d5c8d-hs4mq.istio-system    SYNCED     SYNCED     SYNCED     NOT SENT     istiod-7f8b586864-mv7ht     1.12.0-rc.1
+productpage-v1-6b746f74dc-9stvs.default               STALE      SYNCED     SYNCED     SYNCED       istiod-7f8b586864-mv7ht
+ratings-v1-b6994bb9-gl4kx.default	SYNCED	SYNCED	SYNCED	SYNCED	istiod-7f8b586864-mv7ht	1.11.1
+
+`
+
+	cases := []struct {
+		name       string
+		in         string
+		expectFail bool
+		want       []ProxyInfo
+	}{
+		{
+			"proxy status",
+			output,
+			false,
+			[]ProxyInfo{
+				{ID: "details-v1-5498c86cf5-vnxw5.default", IstioVersion: "1.11.2"},
+				{ID: "istio-ingressgateway-5d9b5d5c8d-hs4mq.istio-system", IstioVersion: "1.12.0-rc.1"},
+				{ID: "ratings-v1-b6994bb9-gl4kx.default", IstioVersion: "1.11.1"},
+			},
+		},
+		{"empty", "", false, nil},
+		{"header only", "NAME CDS LDS EDS RDS ISTIOD VERSION\n", false, nil},
+		{"not proxy status", "Error: no running Istio pods", true, nil},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := ParseProxyStatus(v.in)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestCompatibleControlPlanes(t *testing.T) {
+	controlPlanes := []BuildInfo{
+		{Version: "1.12.0", GitRevision: "canary"},
+		{Version: "1.10.5"},
+		{Version: "1.11.2", GitRevision: "stable"},
+		{Version: "1.9.0"},
+		{Version: "unknown"},
+		{Version: "2.0.0"},
+	}
+
+	cases := []struct {
+		name  string
+		proxy string
+		want  []BuildInfo
+	}{
+		{"one minor skew", "1.11.0", controlPlanes[0:3]},
+		{"newest", "1.12.1", []BuildInfo{controlPlanes[0], controlPlanes[2]}},
+		{"oldest", "1.9.3", []BuildInfo{controlPlanes[1], controlPlanes[3]}},
+		{"unparseable proxy", "unknown", []BuildInfo{}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got := CompatibleControlPlanes(ProxyInfo{ID: "pod.ns", IstioVersion: v.proxy}, controlPlanes)
+			if !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestAllProxiesAtVersion(t *testing.T) {
+	proxies := []ProxyInfo{
+		{ID: "a", IstioVersion: "1.12.0"},
+		{ID: "b", IstioVersion: "v1.12.0+build.1"},
+		{ID: "c", IstioVersion: "1.11.2"},
+		{ID: "d", IstioVersion: "unknown"},
+	}
+
+	cases := []struct {
+		name           string
+		proxies        []ProxyInfo
+		target         string
+		want           bool
+		wantStragglers []ProxyInfo
+	}{
+		{"all caught up", proxies[:2], "1.12.0", true, []ProxyInfo{}},
+		{"stragglers", proxies, "1.12.0", false, proxies[2:]},
+		{"no proxies", nil, "1.12.0", true, []ProxyInfo{}},
+		{"unparseable target", proxies[:2], "latest", false, proxies[:2]},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, stragglers := AllProxiesAtVersion(v.proxies, v.target)
+			if got != v.want || !reflect.DeepEqual(stragglers, v.wantStragglers) {
+				t.Errorf("got %v, %v; want %v, %v", got, stragglers, v.want, v.wantStragglers)
+			}
+		})
+	}
+}
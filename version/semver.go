@@ -0,0 +1,778 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var semverRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?` +
+	`(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?` +
+	`(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+// semver is a parsed semantic version. Istio components frequently report
+// versions without a patch number (e.g. "1.2"), so a missing patch is treated as zero.
+type semver struct {
+	major      int
+	minor      int
+	patch      int
+	prerelease string
+	metadata   string
+}
+
+// parseSemver parses a version of the form [v]major.minor[.patch][-prerelease][+metadata]
+func parseSemver(v string) (semver, error) {
+	m := semverRegexp.FindStringSubmatch(strings.TrimSpace(v))
+	if m == nil {
+		return semver{}, fmt.Errorf("invalid version %q", v)
+	}
+
+	var (
+		res semver
+		err error
+	)
+	if res.major, err = strconv.Atoi(m[1]); err != nil {
+		return semver{}, fmt.Errorf("invalid major version in %q: %v", v, err)
+	}
+	if res.minor, err = strconv.Atoi(m[2]); err != nil {
+		return semver{}, fmt.Errorf("invalid minor version in %q: %v", v, err)
+	}
+	if m[3] != "" {
+		if res.patch, err = strconv.Atoi(m[3]); err != nil {
+			return semver{}, fmt.Errorf("invalid patch version in %q: %v", v, err)
+		}
+	}
+	res.prerelease = m[4]
+	res.metadata = m[5]
+
+	return res, nil
+}
+
+// cleanVersion strips the leading "v" and any build metadata from v, keeping the rest
+// as written. It returns false if v is not a valid version.
+func cleanVersion(v string) (string, bool) {
+	if _, err := parseSemver(v); err != nil {
+		return "", false
+	}
+	v = strings.TrimSpace(v)
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimPrefix(v, "v"), true
+}
+
+// compare returns -1, 0 or 1 depending on whether s has lower, equal or higher
+// precedence than o. Build metadata is ignored, as mandated by the semver spec.
+func (s semver) compare(o semver) int {
+	if c := compareInt(s.major, o.major); c != 0 {
+		return c
+	}
+	if c := compareInt(s.minor, o.minor); c != 0 {
+		return c
+	}
+	if c := compareInt(s.patch, o.patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(s.prerelease, o.prerelease)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparePrerelease orders pre-release strings. A version without a pre-release
+// has higher precedence than one with a pre-release.
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := compareInt(an, bn); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			// numeric identifiers have lower precedence than alphanumeric ones
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInt(len(as), len(bs))
+}
+
+// CompareStrict compares two versions by semantic version precedence, returning -1, 0 or 1
+// depending on whether a is lower than, equal to, or higher than b.
+//
+// Build metadata (anything after "+") is ignored, so "1.11.0+build1" and "1.11.0+build2"
+// are equal. Pre-release identifiers are honored, so "1.11.0-rc.1" is lower than "1.11.0".
+// A leading "v" and a missing patch number (treated as zero) are accepted.
+func CompareStrict(a, b string) (int, error) {
+	av, err := parseSemver(a)
+	if err != nil {
+		return 0, err
+	}
+	bv, err := parseSemver(b)
+	if err != nil {
+		return 0, err
+	}
+	return av.compare(bv), nil
+}
+
+// CompareBy returns a comparator ordering builds by the named fields in priority order, for
+// use with sort.Slice. Fields are named by their JSON keys: "version", "revision",
+// "golang_version", "status", "tag" and "source". Versions are compared by semantic version precedence,
+// with unparseable versions ordered before parseable ones and among themselves as strings;
+// all other fields are compared as strings. Unknown field names are ignored, so that callers
+// can pass through user-supplied sort keys without validating them first.
+func CompareBy(fields ...string) func(a, b BuildInfo) int {
+	return func(a, b BuildInfo) int {
+		for _, field := range fields {
+			var c int
+			switch field {
+			case "version":
+				c = compareVersionStrings(a.Version, b.Version)
+			case "revision":
+				c = strings.Compare(a.GitRevision, b.GitRevision)
+			case "golang_version":
+				c = strings.Compare(a.GolangVersion, b.GolangVersion)
+			case "status":
+				c = strings.Compare(a.BuildStatus, b.BuildStatus)
+			case "tag":
+				c = strings.Compare(a.GitTag, b.GitTag)
+			case "source":
+				c = strings.Compare(a.Source, b.Source)
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+}
+
+// compareVersionStrings orders versions by semantic version precedence. Unparseable versions
+// are ordered before parseable ones, and among themselves as strings.
+func compareVersionStrings(a, b string) int {
+	av, aerr := parseSemver(a)
+	bv, berr := parseSemver(b)
+	switch {
+	case aerr != nil && berr != nil:
+		return strings.Compare(a, b)
+	case aerr != nil:
+		return -1
+	case berr != nil:
+		return 1
+	}
+	return av.compare(bv)
+}
+
+// BumpKind describes the most significant difference between two versions.
+type BumpKind string
+
+const (
+	// NoBump means both versions have the same precedence.
+	NoBump BumpKind = "none"
+	// MajorBump means the major versions differ.
+	MajorBump BumpKind = "major"
+	// MinorBump means the minor versions differ.
+	MinorBump BumpKind = "minor"
+	// PatchBump means the patch versions differ.
+	PatchBump BumpKind = "patch"
+	// PrereleaseBump means only the pre-release portions differ.
+	PrereleaseBump BumpKind = "prerelease"
+)
+
+// VersionBumpKind returns the kind of change between the versions of two builds,
+// regardless of its direction. Build metadata is ignored.
+func VersionBumpKind(from, to BuildInfo) (BumpKind, error) {
+	f, err := parseSemver(from.Version)
+	if err != nil {
+		return "", err
+	}
+	t, err := parseSemver(to.Version)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case f.major != t.major:
+		return MajorBump, nil
+	case f.minor != t.minor:
+		return MinorBump, nil
+	case f.patch != t.patch:
+		return PatchBump, nil
+	case comparePrerelease(f.prerelease, t.prerelease) != 0:
+		return PrereleaseBump, nil
+	}
+	return NoBump, nil
+}
+
+// NextVersion returns the version following current for a bump of "major", "minor" or
+// "patch", as in MajorBump, MinorBump and PatchBump. Lower components are reset to zero, and
+// any pre-release and build metadata are cleared: a minor bump of 1.11.2-rc.1 gives 1.12.0.
+func NextVersion(current string, bump string) (string, error) {
+	ver, err := parseSemver(current)
+	if err != nil {
+		return "", err
+	}
+
+	switch BumpKind(bump) {
+	case MajorBump:
+		ver = semver{major: ver.major + 1}
+	case MinorBump:
+		ver = semver{major: ver.major, minor: ver.minor + 1}
+	case PatchBump:
+		ver = semver{major: ver.major, minor: ver.minor, patch: ver.patch + 1}
+	default:
+		return "", fmt.Errorf("unknown bump %q: must be %q, %q or %q", bump, MajorBump, MinorBump, PatchBump)
+	}
+	return fmt.Sprintf("%d.%d.%d", ver.major, ver.minor, ver.patch), nil
+}
+
+// DescribeChange produces a human-readable sentence describing the change between two builds.
+//
+// This looks like:
+//
+// ```
+// upgrade from 1.11.2 to 1.12.0 (minor)
+// ```
+func DescribeChange(from, to BuildInfo) string {
+	kind, err := VersionBumpKind(from, to)
+	if err != nil {
+		return fmt.Sprintf("change from %s to %s", from.Version, to.Version)
+	}
+	if kind == NoBump {
+		return "no change"
+	}
+
+	// both versions parsed successfully in VersionBumpKind
+	f, _ := parseSemver(from.Version)
+	t, _ := parseSemver(to.Version)
+	direction := "upgrade"
+	if f.compare(t) > 0 {
+		direction = "downgrade"
+	}
+	return fmt.Sprintf("%s from %s to %s (%s)", direction, from.Version, to.Version, kind)
+}
+
+// PatchBehind returns how many patch releases the build is behind latestInMinor, the
+// latest release of the same major.minor. It is zero when the build is at or ahead of it.
+// An error is returned if either version cannot be parsed or they belong to different minors.
+func (b BuildInfo) PatchBehind(latestInMinor string) (int, error) {
+	cur, err := parseSemver(b.Version)
+	if err != nil {
+		return 0, err
+	}
+	latest, err := parseSemver(latestInMinor)
+	if err != nil {
+		return 0, err
+	}
+	if cur.major != latest.major || cur.minor != latest.minor {
+		return 0, fmt.Errorf("version %s is not in the same minor as %s", b.Version, latestInMinor)
+	}
+	if latest.patch <= cur.patch {
+		return 0, nil
+	}
+	return latest.patch - cur.patch, nil
+}
+
+// MinorsBehind returns how many minor releases from is behind to. The result is negative
+// when from is ahead of to. Both versions must share the same major version.
+func MinorsBehind(from, to BuildInfo) (int, error) {
+	f, err := parseSemver(from.Version)
+	if err != nil {
+		return 0, err
+	}
+	t, err := parseSemver(to.Version)
+	if err != nil {
+		return 0, err
+	}
+	if f.major != t.major {
+		return 0, fmt.Errorf("versions %s and %s have different major versions", from.Version, to.Version)
+	}
+	return t.minor - f.minor, nil
+}
+
+// MinorsBetween returns the major.minor release trains strictly between two versions, in
+// ascending order: from 1.9.0 to 1.12.0 gives ["1.10", "1.11"]. These are the intermediate
+// hops of a step-by-step upgrade. Both versions must share the same major version, and from
+// must not be greater than to.
+func MinorsBetween(from, to string) ([]string, error) {
+	f, err := parseSemver(from)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseSemver(to)
+	if err != nil {
+		return nil, err
+	}
+	if f.compare(t) > 0 {
+		return nil, fmt.Errorf("version %s is greater than %s", from, to)
+	}
+	if f.major != t.major {
+		return nil, fmt.Errorf("versions %s and %s have different major versions", from, to)
+	}
+
+	res := []string{}
+	for minor := f.minor + 1; minor < t.minor; minor++ {
+		res = append(res, fmt.Sprintf("%d.%d", f.major, minor))
+	}
+	return res, nil
+}
+
+// ShareUpgradeWindow reports whether two versions are at most one minor release apart, in
+// either direction, so that a single upgrade step keeps them compatible and the components
+// running them can be upgraded in stages rather than in lockstep. Versions with different
+// major versions, or that cannot be parsed, never share a window.
+func ShareUpgradeWindow(a, b string) bool {
+	minors, err := MinorsBehind(BuildInfo{Version: a}, BuildInfo{Version: b})
+	if err != nil {
+		return false
+	}
+	return minors >= -1 && minors <= 1
+}
+
+// UpgradeRisk returns a coarse risk rating for moving from one build to another:
+//
+//   - "none" when the versions are equal
+//   - "low" for patch or pre-release changes
+//   - "medium" for a single minor version upgrade
+//   - "high" for major version upgrades or upgrades skipping a minor version
+//   - "invalid" for downgrades and unparseable versions
+func UpgradeRisk(from, to BuildInfo) string {
+	kind, err := VersionBumpKind(from, to)
+	if err != nil {
+		return "invalid"
+	}
+	if c, _ := CompareStrict(from.Version, to.Version); c > 0 {
+		return "invalid"
+	}
+
+	switch kind {
+	case NoBump:
+		return "none"
+	case PatchBump, PrereleaseBump:
+		return "low"
+	case MinorBump:
+		if minors, _ := MinorsBehind(from, to); minors == 1 {
+			return "medium"
+		}
+	}
+	return "high"
+}
+
+// CanRollback reports whether a rollback from one build to an older one is supported: a patch
+// or pre-release rollback, or a rollback of a single minor version. When the rollback is not
+// supported, the returned string explains why.
+func CanRollback(from, to BuildInfo) (bool, string) {
+	c, err := CompareStrict(from.Version, to.Version)
+	if err != nil {
+		return false, err.Error()
+	}
+	if c < 0 {
+		return false, fmt.Sprintf("%s is newer than %s; not a rollback", to.Version, from.Version)
+	}
+
+	minors, err := MinorsBehind(to, from)
+	if err != nil {
+		return false, err.Error()
+	}
+	if minors > 1 {
+		return false, fmt.Sprintf("rolling back from %s to %s crosses %d minor versions; at most 1 is supported",
+			from.Version, to.Version, minors)
+	}
+	return true, ""
+}
+
+// CompatibilityScore rates how compatible a candidate build is with a target build, so that
+// the most compatible of several candidates can be selected. Build metadata is ignored.
+//
+//   - 100 for the same version
+//   - 90 minus the patch difference, but at least 60, for the same major.minor
+//     (a pre-release of the same patch scores 90)
+//   - 50 for a one minor version skew in either direction
+//   - 0 for larger skews and different major versions
+//   - -1 when either version is unparseable
+func CompatibilityScore(candidate, target BuildInfo) int {
+	c, err := parseSemver(candidate.Version)
+	if err != nil {
+		return -1
+	}
+	t, err := parseSemver(target.Version)
+	if err != nil {
+		return -1
+	}
+
+	switch {
+	case c.compare(t) == 0:
+		return 100
+	case c.major != t.major:
+		return 0
+	case c.minor == t.minor:
+		score := 90 - abs(c.patch-t.patch)
+		if score < 60 {
+			score = 60
+		}
+		return score
+	case abs(c.minor-t.minor) == 1:
+		return 50
+	}
+	return 0
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// WireOptions adjusts the check made by WireCompatibleWith.
+type WireOptions struct {
+	// Compatible, when set, replaces the default major version check.
+	Compatible func(a, b BuildInfo) bool
+}
+
+// WireCompatible reports whether two components can negotiate a protocol with each other.
+// It assumes that wire-breaking changes are only made in major versions, so builds are
+// compatible exactly when their major versions match. Unparseable versions are incompatible.
+// Use WireCompatibleWith to replace this assumption.
+func WireCompatible(a, b BuildInfo) bool {
+	return WireCompatibleWith(a, b, WireOptions{})
+}
+
+// WireCompatibleWith is WireCompatible, with the compatibility check overridable through opts.
+func WireCompatibleWith(a, b BuildInfo, opts WireOptions) bool {
+	if opts.Compatible != nil {
+		return opts.Compatible(a, b)
+	}
+	x, err := parseSemver(a.Version)
+	if err != nil {
+		return false
+	}
+	y, err := parseSemver(b.Version)
+	if err != nil {
+		return false
+	}
+	return x.major == y.major
+}
+
+// EffectiveVersion returns the version to use for comparisons. The precedence is:
+//
+//  1. Version, when it is a valid semantic version
+//  2. GitTag, when it is a valid semantic version
+//  3. Version, as is
+//
+// This makes builds with an unknown Version but a meaningful GitTag comparable.
+func (b BuildInfo) EffectiveVersion() string {
+	if _, err := parseSemver(b.Version); err == nil {
+		return b.Version
+	}
+	if _, err := parseSemver(b.GitTag); err == nil {
+		return b.GitTag
+	}
+	return b.Version
+}
+
+// WithEffectiveVersion returns a copy of the build information with Version replaced by
+// EffectiveVersion. Passing the result to the comparison helpers makes them fall back to
+// GitTag for tag-only builds.
+func (b BuildInfo) WithEffectiveVersion() BuildInfo {
+	b.Version = b.EffectiveVersion()
+	return b
+}
+
+// Train returns the release train of the build, as major.minor, or an empty string
+// when the version cannot be parsed.
+func (b BuildInfo) Train() string {
+	ver, err := parseSemver(b.Version)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d", ver.major, ver.minor)
+}
+
+// ReleaseBranch returns the git branch the build is released from, following Istio's
+// release-<major>.<minor> convention, such as "release-1.11". Development builds, whose
+// version is unknown or unparseable, return DevelopmentBranch.
+func (b BuildInfo) ReleaseBranch() string {
+	if train := b.Train(); train != "" {
+		return "release-" + train
+	}
+	return DevelopmentBranch
+}
+
+// IsEOL reports whether the build's release train is not among supportedMinors, given as
+// major.minor values such as "1.11", along with a message suggesting an upgrade, which lists
+// the supported releases when there are any. Builds with an unparseable version are never
+// reported as end of life.
+func (b BuildInfo) IsEOL(supportedMinors []string) (bool, string) {
+	train := b.Train()
+	if train == "" {
+		return false, ""
+	}
+	for _, minor := range supportedMinors {
+		if (BuildInfo{Version: minor}).Train() == train {
+			return false, ""
+		}
+	}
+	msg := fmt.Sprintf("%s is end of life; upgrade to a supported release", train)
+	if len(supportedMinors) > 0 {
+		msg += fmt.Sprintf(" (%s)", strings.Join(supportedMinors, ", "))
+	}
+	return true, msg
+}
+
+// snapshotDateRegexp matches a date-like pre-release identifier: 20060102 or 2006-01-02.
+var snapshotDateRegexp = regexp.MustCompile(`(^|[.-])(20\d{6}|20\d{2}-\d{2}-\d{2})([.-]|$)`)
+
+// IsSnapshot reports whether the build is a nightly or development build rather than a release.
+// The patterns recognized are:
+//
+//   - a version containing "snapshot" or "nightly", in any case
+//   - a pre-release containing a date, as 20060102 or 2006-01-02
+//   - an empty or "unknown" version
+//   - a "Modified" or "dirty" build status, in any case
+func (b BuildInfo) IsSnapshot() bool {
+	if b.Version == "" || b.Version == "unknown" {
+		return true
+	}
+	if status := strings.ToLower(b.BuildStatus); status == "modified" || status == "dirty" {
+		return true
+	}
+	lower := strings.ToLower(b.Version)
+	if strings.Contains(lower, "snapshot") || strings.Contains(lower, "nightly") {
+		return true
+	}
+	ver, err := parseSemver(b.Version)
+	return err == nil && snapshotDateRegexp.MatchString(ver.prerelease)
+}
+
+// IsProductionReady reports whether the build is a clean release: a parseable version without
+// a pre-release, a "Clean" build status, and not a snapshot as defined by IsSnapshot.
+func (b BuildInfo) IsProductionReady() bool {
+	ver, err := parseSemver(b.Version)
+	if err != nil || ver.prerelease != "" {
+		return false
+	}
+	return strings.EqualFold(b.BuildStatus, "Clean") && !b.IsSnapshot()
+}
+
+// IsPrereleaseBump reports whether to is a later pre-release of the same release as from,
+// such as 1.12.0-rc.1 to 1.12.0-rc.2. Moving from a pre-release to the final release is not
+// a pre-release bump, as the final release is the actual version change.
+func IsPrereleaseBump(from, to BuildInfo) bool {
+	f, err := parseSemver(from.Version)
+	if err != nil {
+		return false
+	}
+	t, err := parseSemver(to.Version)
+	if err != nil {
+		return false
+	}
+	if f.prerelease == "" || t.prerelease == "" {
+		return false
+	}
+	return f.major == t.major && f.minor == t.minor && f.patch == t.patch &&
+		comparePrerelease(f.prerelease, t.prerelease) < 0
+}
+
+// FeatureEnabledForVersion reads the minimum version required by a feature from envVar, such
+// as ISTIO_FEATURE_MINVERSION=1.12, and reports whether the current build satisfies it.
+// An unset or empty variable enables the feature. An error is only returned when the variable
+// holds a malformed version; a current build with an unparseable version is not enabled.
+func FeatureEnabledForVersion(envVar string, current BuildInfo) (bool, error) {
+	value, ok := os.LookupEnv(envVar)
+	if !ok || strings.TrimSpace(value) == "" {
+		return true, nil
+	}
+	min, err := parseSemver(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid minimum version in %s: %v", envVar, err)
+	}
+	cur, err := parseSemver(current.Version)
+	if err != nil {
+		return false, nil
+	}
+	return cur.compare(min) >= 0, nil
+}
+
+// maxVersionIntMajor is the highest major version VersionInt can encode without overflowing.
+const maxVersionIntMajor = (math.MaxInt64 - 999999) / 1000000
+
+// VersionInt encodes the version as major*1000000 + minor*1000 + patch, so that versions sort
+// numerically, e.g. for indexing in a database. Minor and patch numbers must therefore be below
+// 1000, and the major version at most 9223372036853 so that the result fits in an int64.
+// Pre-release and build metadata are dropped, so 1.12.0-rc.1 encodes the same as 1.12.0.
+func (b BuildInfo) VersionInt() (int64, error) {
+	ver, err := parseSemver(b.Version)
+	if err != nil {
+		return 0, err
+	}
+	if ver.minor >= 1000 || ver.patch >= 1000 {
+		return 0, fmt.Errorf("version %s cannot be encoded: minor and patch must be below 1000", b.Version)
+	}
+	if int64(ver.major) > maxVersionIntMajor {
+		return 0, fmt.Errorf("version %s cannot be encoded: major must be at most %d", b.Version, int64(maxVersionIntMajor))
+	}
+	return int64(ver.major)*1000000 + int64(ver.minor)*1000 + int64(ver.patch), nil
+}
+
+// Latest returns the highest of the given versions by semantic version precedence, as
+// written in the input. Unparseable entries are ignored; an error is returned if none parse.
+// Following semver, a pre-release never wins over the release of the same version
+// (1.12.0 beats 1.12.0-rc.1), but does win over lower releases (1.13.0-rc.1 beats 1.12.0).
+// Among versions differing only in build metadata, the first one wins.
+func Latest(versions []string) (string, error) {
+	latest, _, ok := latestVersion(versions)
+	if !ok {
+		return "", fmt.Errorf("no valid version in %v", versions)
+	}
+	return latest, nil
+}
+
+// NormalizeVersions cleans up a list of versions: a leading "v" and build metadata are removed,
+// duplicates are dropped, and the result is sorted by semantic version precedence, with
+// versions of equal precedence (such as "1.2" and "1.2.0") ordered as strings. Unparseable
+// entries are kept, deduplicated, after all valid versions and sorted as strings.
+func NormalizeVersions(versions []string) []string {
+	type parsed struct {
+		raw string
+		ver semver
+	}
+	var (
+		valid   []parsed
+		invalid []string
+		seen    = make(map[string]bool)
+	)
+	for _, v := range versions {
+		if clean, ok := cleanVersion(v); ok {
+			v = clean
+		}
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		if ver, err := parseSemver(v); err == nil {
+			valid = append(valid, parsed{raw: v, ver: ver})
+		} else {
+			invalid = append(invalid, v)
+		}
+	}
+
+	sort.Slice(valid, func(i, j int) bool {
+		if c := valid[i].ver.compare(valid[j].ver); c != 0 {
+			return c < 0
+		}
+		return valid[i].raw < valid[j].raw
+	})
+	sort.Strings(invalid)
+
+	res := make([]string, 0, len(valid)+len(invalid))
+	for _, v := range valid {
+		res = append(res, v.raw)
+	}
+	return append(res, invalid...)
+}
+
+func latestVersion(versions []string) (string, semver, bool) {
+	var (
+		latestRaw string
+		latest    semver
+		found     bool
+	)
+	for _, v := range versions {
+		ver, err := parseSemver(v)
+		if err != nil {
+			continue
+		}
+		if !found || ver.compare(latest) > 0 {
+			latestRaw, latest, found = v, ver, true
+		}
+	}
+	return latestRaw, latest, found
+}
+
+// prereleaseStages are the pre-release stages recognized by PrereleaseStage, in order of maturity.
+var prereleaseStages = []string{"alpha", "beta", "rc"}
+
+// PrereleaseStage returns the maturity stage of a pre-release build: "alpha", "beta" or "rc",
+// based on the prefix of the first pre-release identifier (so both "rc.1" and "rc1" are "rc").
+// Unrecognized pre-releases are returned as is. Releases and unparseable versions return "".
+func (b BuildInfo) PrereleaseStage() string {
+	ver, err := parseSemver(b.Version)
+	if err != nil || ver.prerelease == "" {
+		return ""
+	}
+	first := strings.ToLower(strings.SplitN(ver.prerelease, ".", 2)[0])
+	for _, stage := range prereleaseStages {
+		if strings.HasPrefix(first, stage) {
+			return stage
+		}
+	}
+	return ver.prerelease
+}
+
+// UpdateAvailable calls fetchLatest to obtain the latest released version and reports whether
+// it is newer than the current build, along with a message for the user. Fetching is left to
+// the caller, so that this package stays free of network access. When the current build is
+// newer than the latest release, as happens when testing pre-releases, no update is reported.
+func UpdateAvailable(current BuildInfo, fetchLatest func() (string, error)) (bool, string, error) {
+	latest, err := fetchLatest()
+	if err != nil {
+		return false, "", fmt.Errorf("failed to fetch the latest version: %v", err)
+	}
+	c, err := CompareStrict(current.Version, latest)
+	if err != nil {
+		return false, "", err
+	}
+
+	switch {
+	case c < 0:
+		return true, fmt.Sprintf("version %s is available (current version is %s)", latest, current.Version), nil
+	case c > 0:
+		return false, fmt.Sprintf("version %s is newer than the latest release %s", current.Version, latest), nil
+	}
+	return false, fmt.Sprintf("version %s is the latest release", current.Version), nil
+}
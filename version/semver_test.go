@@ -0,0 +1,825 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSemver(t *testing.T) {
+	cases := []struct {
+		in         string
+		expectFail bool
+		want       semver
+	}{
+		{"1.11.2", false, semver{major: 1, minor: 11, patch: 2}},
+		{"v1.11.2", false, semver{major: 1, minor: 11, patch: 2}},
+		{"1.2", false, semver{major: 1, minor: 2}},
+		{"1.12.0-rc.1+build.5", false, semver{major: 1, minor: 12, prerelease: "rc.1", metadata: "build.5"}},
+		{"unknown", true, semver{}},
+		{"1", true, semver{}},
+		{"1.2.3.4", true, semver{}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.in, func(t *testing.T) {
+			got, err := parseSemver(v.in)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if got != v.want {
+				t.Errorf("got %+v; want %+v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	cases := []struct {
+		current    string
+		bump       string
+		expectFail bool
+		want       string
+	}{
+		{"1.11.2", "patch", false, "1.11.3"},
+		{"1.11.2", "minor", false, "1.12.0"},
+		{"1.11.2", "major", false, "2.0.0"},
+		{"v1.9.9", "minor", false, "1.10.0"},
+		{"1.2", "patch", false, "1.2.1"},
+		{"1.11.2-rc.1+build.5", "minor", false, "1.12.0"},
+		{"1.11.2", "prerelease", true, ""},
+		{"1.11.2", "", true, ""},
+		{"unknown", "patch", true, ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.current+" "+v.bump, func(t *testing.T) {
+			got, err := NextVersion(v.current, v.bump)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestDescribeChange(t *testing.T) {
+	cases := []struct {
+		from string
+		to   string
+		kind BumpKind
+		want string
+	}{
+		{"1.11.2", "1.12.0", MinorBump, "upgrade from 1.11.2 to 1.12.0 (minor)"},
+		{"1.12.0", "1.11.5", MinorBump, "downgrade from 1.12.0 to 1.11.5 (minor)"},
+		{"1.11.2", "1.11.3", PatchBump, "upgrade from 1.11.2 to 1.11.3 (patch)"},
+		{"1.11.2", "2.0.0", MajorBump, "upgrade from 1.11.2 to 2.0.0 (major)"},
+		{"1.12.0-rc.1", "1.12.0", PrereleaseBump, "upgrade from 1.12.0-rc.1 to 1.12.0 (prerelease)"},
+		{"1.11.2", "1.11.2+build.2", NoBump, "no change"},
+		{"unknown", "1.11.2", "", "change from unknown to 1.11.2"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.from+"->"+v.to, func(t *testing.T) {
+			from, to := BuildInfo{Version: v.from}, BuildInfo{Version: v.to}
+			kind, _ := VersionBumpKind(from, to)
+			if kind != v.kind {
+				t.Errorf("got kind %q; want %q", kind, v.kind)
+			}
+			if got := DescribeChange(from, to); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestCompareStrict(t *testing.T) {
+	cases := []struct {
+		a          string
+		b          string
+		expectFail bool
+		want       int
+	}{
+		{"1.11.0", "1.11.0", false, 0},
+		{"1.11.0+build1", "1.11.0+build2", false, 0},
+		{"1.11.0+build1", "1.11.0", false, 0},
+		{"v1.11", "1.11.0", false, 0},
+		{"1.11.0-rc.1", "1.11.0", false, -1},
+		{"1.11.0", "1.11.0-rc.1", false, 1},
+		{"1.11.0-rc.1+build1", "1.11.0-rc.1+build2", false, 0},
+		{"1.11.0-rc.1", "1.11.0-rc.2", false, -1},
+		{"1.11.0-rc.2", "1.11.0-rc.10", false, -1},
+		{"1.11.0-alpha", "1.11.0-beta", false, -1},
+		{"1.11.0-alpha", "1.11.0-alpha.1", false, -1},
+		{"1.11.0-1", "1.11.0-alpha", false, -1},
+		{"1.10.9", "1.11.0", false, -1},
+		{"2.0.0", "1.99.99", false, 1},
+		{"unknown", "1.11.0", true, 0},
+		{"1.11.0", "", true, 0},
+	}
+
+	for _, v := range cases {
+		t.Run(v.a+" vs "+v.b, func(t *testing.T) {
+			got, err := CompareStrict(v.a, v.b)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if got != v.want {
+				t.Errorf("got %d; want %d", got, v.want)
+			}
+		})
+	}
+}
+
+func TestCompareBy(t *testing.T) {
+	cases := []struct {
+		name   string
+		fields []string
+		a      BuildInfo
+		b      BuildInfo
+		want   int
+	}{
+		{"version", []string{"version"}, BuildInfo{Version: "1.9.0"}, BuildInfo{Version: "1.10.0"}, -1},
+		{"version ignores metadata", []string{"version"}, BuildInfo{Version: "1.10.0+b"}, BuildInfo{Version: "v1.10.0"}, 0},
+		{"unparseable first", []string{"version"}, BuildInfo{Version: "1.10.0"}, BuildInfo{Version: "unknown"}, 1},
+		{"both unparseable", []string{"version"}, BuildInfo{Version: "dev"}, BuildInfo{Version: "unknown"}, -1},
+		{
+			"tiebreak",
+			[]string{"version", "revision"},
+			BuildInfo{Version: "1.10.0", GitRevision: "def"},
+			BuildInfo{Version: "1.10.0", GitRevision: "abc"},
+			1,
+		},
+		{
+			"priority",
+			[]string{"status", "version"},
+			BuildInfo{Version: "1.12.0", BuildStatus: "Clean"},
+			BuildInfo{Version: "1.10.0", BuildStatus: "Modified"},
+			-1,
+		},
+		{"other fields", []string{"golang_version", "tag"}, BuildInfo{GitTag: "b"}, BuildInfo{GitTag: "a"}, 1},
+		{"source", []string{"source", "version"}, BuildInfo{Version: "1.12.0", Source: "ci"}, BuildInfo{Version: "1.10.0", Source: "local"}, -1},
+		{"unknown field ignored", []string{"vendor", "version"}, BuildInfo{Version: "1.12.0"}, BuildInfo{Version: "1.10.0"}, 1},
+		{"no fields", nil, BuildInfo{Version: "1.12.0"}, BuildInfo{Version: "1.10.0"}, 0},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			cmp := CompareBy(v.fields...)
+			if got := cmp(v.a, v.b); got != v.want {
+				t.Errorf("got %d; want %d", got, v.want)
+			}
+			if got := cmp(v.b, v.a); got != -v.want {
+				t.Errorf("got %d reversed; want %d", got, -v.want)
+			}
+		})
+	}
+}
+
+func TestPatchBehind(t *testing.T) {
+	cases := []struct {
+		current    string
+		latest     string
+		expectFail bool
+		want       int
+	}{
+		{"1.11.2", "1.11.5", false, 3},
+		{"1.11.5", "1.11.5", false, 0},
+		{"1.11.6", "1.11.5", false, 0},
+		{"1.11.0-rc.1", "1.11.1", false, 1},
+		{"1.11", "1.11.2", false, 2},
+		{"1.11.2", "1.12.0", true, 0},
+		{"1.11.2", "2.11.5", true, 0},
+		{"unknown", "1.11.5", true, 0},
+		{"1.11.2", "latest", true, 0},
+	}
+
+	for _, v := range cases {
+		t.Run(v.current+" to "+v.latest, func(t *testing.T) {
+			got, err := BuildInfo{Version: v.current}.PatchBehind(v.latest)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if got != v.want {
+				t.Errorf("got %d; want %d", got, v.want)
+			}
+		})
+	}
+}
+
+func TestMinorsBehind(t *testing.T) {
+	cases := []struct {
+		from       string
+		to         string
+		expectFail bool
+		want       int
+	}{
+		{"1.9.0", "1.12.3", false, 3},
+		{"1.12.3", "1.12.0", false, 0},
+		{"1.12.0", "1.11.5", false, -1},
+		{"1.12.0", "2.0.0", true, 0},
+		{"unknown", "1.12.0", true, 0},
+	}
+
+	for _, v := range cases {
+		t.Run(v.from+" to "+v.to, func(t *testing.T) {
+			got, err := MinorsBehind(BuildInfo{Version: v.from}, BuildInfo{Version: v.to})
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if got != v.want {
+				t.Errorf("got %d; want %d", got, v.want)
+			}
+		})
+	}
+}
+
+func TestMinorsBetween(t *testing.T) {
+	cases := []struct {
+		from       string
+		to         string
+		expectFail bool
+		want       []string
+	}{
+		{"1.9.0", "1.12.0", false, []string{"1.10", "1.11"}},
+		{"v1.9.3", "1.12", false, []string{"1.10", "1.11"}},
+		{"1.11.2", "1.12.0", false, []string{}},
+		{"1.11.2", "1.11.5", false, []string{}},
+		{"1.12.0", "1.9.0", true, nil},
+		{"1.9.0", "2.1.0", true, nil},
+		{"unknown", "1.12.0", true, nil},
+	}
+
+	for _, v := range cases {
+		t.Run(v.from+" to "+v.to, func(t *testing.T) {
+			got, err := MinorsBetween(v.from, v.to)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %#v; want %#v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestShareUpgradeWindow(t *testing.T) {
+	cases := []struct {
+		a    string
+		b    string
+		want bool
+	}{
+		{"1.11.2", "1.11.0", true},
+		{"1.11.2", "1.12.0", true},
+		{"1.12.0", "1.11.2", true},
+		{"1.10.5", "1.12.0", false},
+		{"1.12.0", "1.10.5", false},
+		{"1.12.0-rc.1", "1.11.0", true},
+		{"1.12.0", "2.0.0", false},
+		{"unknown", "1.11.0", false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.a+" and "+v.b, func(t *testing.T) {
+			if got := ShareUpgradeWindow(v.a, v.b); got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestUpgradeRisk(t *testing.T) {
+	cases := []struct {
+		from string
+		to   string
+		want string
+	}{
+		{"1.11.2", "1.11.2", "none"},
+		{"1.11.2", "1.11.2+build.1", "none"},
+		{"1.11.2", "1.11.5", "low"},
+		{"1.12.0-rc.1", "1.12.0", "low"},
+		{"1.11.2", "1.12.0", "medium"},
+		{"1.10.2", "1.12.0", "high"},
+		{"1.11.2", "2.0.0", "high"},
+		{"1.12.0", "1.11.5", "invalid"},
+		{"1.11.5", "1.11.2", "invalid"},
+		{"unknown", "1.11.2", "invalid"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.from+" to "+v.to, func(t *testing.T) {
+			if got := UpgradeRisk(BuildInfo{Version: v.from}, BuildInfo{Version: v.to}); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestCanRollback(t *testing.T) {
+	cases := []struct {
+		from   string
+		to     string
+		want   bool
+		reason string
+	}{
+		{"1.11.2", "1.11.2", true, ""},
+		{"1.11.5", "1.11.2", true, ""},
+		{"1.12.0", "1.12.0-rc.1", true, ""},
+		{"1.12.0", "1.11.5", true, ""},
+		{"1.12.0", "1.10.5", false, "crosses 2 minor versions"},
+		{"2.0.0", "1.12.0", false, "different major versions"},
+		{"1.11.2", "1.12.0", false, "not a rollback"},
+		{"unknown", "1.11.2", false, "unknown"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.from+" to "+v.to, func(t *testing.T) {
+			got, reason := CanRollback(BuildInfo{Version: v.from}, BuildInfo{Version: v.to})
+			if got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+			if !strings.Contains(reason, v.reason) || (v.reason == "") != (reason == "") {
+				t.Errorf("got reason %q; want it to contain %q", reason, v.reason)
+			}
+		})
+	}
+}
+
+func TestCompatibilityScore(t *testing.T) {
+	cases := []struct {
+		candidate string
+		target    string
+		want      int
+	}{
+		{"1.11.2", "1.11.2", 100},
+		{"v1.11.2+build.1", "1.11.2", 100},
+		{"1.11.2-rc.1", "1.11.2", 90},
+		{"1.11.3", "1.11.2", 89},
+		{"1.11.0", "1.11.2", 88},
+		{"1.11.60", "1.11.2", 60},
+		{"1.12.0", "1.11.2", 50},
+		{"1.10.5", "1.11.2", 50},
+		{"1.9.0", "1.11.2", 0},
+		{"2.11.2", "1.11.2", 0},
+		{"unknown", "1.11.2", -1},
+		{"1.11.2", "", -1},
+	}
+
+	for _, v := range cases {
+		t.Run(v.candidate+" for "+v.target, func(t *testing.T) {
+			if got := CompatibilityScore(BuildInfo{Version: v.candidate}, BuildInfo{Version: v.target}); got != v.want {
+				t.Errorf("got %d; want %d", got, v.want)
+			}
+		})
+	}
+}
+
+func TestWireCompatible(t *testing.T) {
+	sameMinor := WireOptions{Compatible: func(a, b BuildInfo) bool {
+		return a.Train() == b.Train()
+	}}
+
+	cases := []struct {
+		a    string
+		b    string
+		opts WireOptions
+		want bool
+	}{
+		{"1.11.2", "1.11.2", WireOptions{}, true},
+		{"1.9.0", "1.12.0-rc.1", WireOptions{}, true},
+		{"1.12.0", "2.0.0", WireOptions{}, false},
+		{"unknown", "1.12.0", WireOptions{}, false},
+		{"1.12.0", "", WireOptions{}, false},
+		{"1.12.0", "1.12.3", sameMinor, true},
+		{"1.11.2", "1.12.0", sameMinor, false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.a+" and "+v.b, func(t *testing.T) {
+			a, b := BuildInfo{Version: v.a}, BuildInfo{Version: v.b}
+			if got := WireCompatibleWith(a, b, v.opts); got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+			if v.opts.Compatible == nil && WireCompatible(a, b) != v.want {
+				t.Errorf("WireCompatible disagrees with WireCompatibleWith")
+			}
+		})
+	}
+}
+
+func TestEffectiveVersion(t *testing.T) {
+	cases := []struct {
+		name    string
+		version string
+		tag     string
+		want    string
+	}{
+		{"version", "1.11.2", "1.11.1", "1.11.2"},
+		{"tag fallback", "unknown", "1.11.2", "1.11.2"},
+		{"v tag fallback", "", "v1.11.2", "v1.11.2"},
+		{"neither", "unknown", "unknown", "unknown"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			in := BuildInfo{Version: v.version, GitTag: v.tag}
+			if got := in.EffectiveVersion(); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+			if got := in.WithEffectiveVersion(); got.Version != v.want || got.GitTag != v.tag {
+				t.Errorf("got %v; want version %q", got, v.want)
+			}
+		})
+	}
+
+	from := BuildInfo{Version: "unknown", GitTag: "1.11.2"}
+	to := BuildInfo{Version: "1.12.0"}
+	if got := DescribeChange(from.WithEffectiveVersion(), to); got != "upgrade from 1.11.2 to 1.12.0 (minor)" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestReleaseBranch(t *testing.T) {
+	cases := []struct {
+		version string
+		want    string
+	}{
+		{"1.11.2", "release-1.11"},
+		{"v1.12.0-rc.1", "release-1.12"},
+		{"1.2", "release-1.2"},
+		{"unknown", "master"},
+		{"", "master"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version, func(t *testing.T) {
+			if got := (BuildInfo{Version: v.version}).ReleaseBranch(); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+
+	defer func(branch string) { DevelopmentBranch = branch }(DevelopmentBranch)
+	DevelopmentBranch = "main"
+	if got := (BuildInfo{Version: "unknown"}).ReleaseBranch(); got != "main" {
+		t.Errorf("got %q; want %q", got, "main")
+	}
+}
+
+func TestIsEOL(t *testing.T) {
+	supported := []string{"1.10", "1.11", "1.12.0"}
+	cases := []struct {
+		version string
+		wantEOL bool
+		wantMsg string
+	}{
+		{"1.11.2", false, ""},
+		{"1.12.3-rc.1", false, ""},
+		{"1.9.8", true, "1.9 is end of life; upgrade to a supported release (1.10, 1.11, 1.12.0)"},
+		{"2.10.0", true, "2.10 is end of life; upgrade to a supported release (1.10, 1.11, 1.12.0)"},
+		{"unknown", false, ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version, func(t *testing.T) {
+			eol, msg := BuildInfo{Version: v.version}.IsEOL(supported)
+			if eol != v.wantEOL {
+				t.Errorf("got %v; want %v", eol, v.wantEOL)
+			}
+			if msg != v.wantMsg {
+				t.Errorf("got %q; want %q", msg, v.wantMsg)
+			}
+		})
+	}
+
+	for _, none := range [][]string{nil, {}} {
+		eol, msg := BuildInfo{Version: "1.11.2"}.IsEOL(none)
+		if want := "1.11 is end of life; upgrade to a supported release"; !eol || msg != want {
+			t.Errorf("got %v, %q; want true, %q", eol, msg, want)
+		}
+	}
+
+	if got := (BuildInfo{Version: "v1.11.2"}).Train(); got != "1.11" {
+		t.Errorf("got train %q; want %q", got, "1.11")
+	}
+}
+
+func TestIsSnapshot(t *testing.T) {
+	cases := []struct {
+		version string
+		status  string
+		want    bool
+	}{
+		{"1.11.2", "Clean", false},
+		{"1.12.0-rc.1", "Clean", false},
+		{"1.12.0-alpha.3c0a9a4c", "Clean", false},
+		{"1.12.0-alpha.20210901", "Clean", true},
+		{"1.12.0-20210901.1", "Clean", true},
+		{"1.12.0-alpha.2021-09-01", "Clean", true},
+		{"1.12.0-SNAPSHOT", "Clean", true},
+		{"1.12-nightly", "Clean", true},
+		{"1.11.2", "Modified", true},
+		{"1.11.2", "dirty", true},
+		{"unknown", "Clean", true},
+		{"", "", true},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version+" "+v.status, func(t *testing.T) {
+			if got := (BuildInfo{Version: v.version, BuildStatus: v.status}).IsSnapshot(); got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestIsProductionReady(t *testing.T) {
+	cases := []struct {
+		version string
+		status  string
+		want    bool
+	}{
+		{"1.11.2", "Clean", true},
+		{"v1.11.2+build.1", "clean", true},
+		{"1.12.0-rc.1", "Clean", false},
+		{"1.11.2", "Modified", false},
+		{"1.11.2", "unknown", false},
+		{"1.11.2-nightly", "Clean", false},
+		{"1.11.2-SNAPSHOT+build.1", "Clean", false},
+		{"unknown", "Clean", false},
+		{"latest", "Clean", false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version+" "+v.status, func(t *testing.T) {
+			if got := (BuildInfo{Version: v.version, BuildStatus: v.status}).IsProductionReady(); got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestIsPrereleaseBump(t *testing.T) {
+	cases := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{"1.12.0-rc.1", "1.12.0-rc.2", true},
+		{"1.12.0-beta.3", "1.12.0-rc.0", true},
+		{"1.12.0-rc.2", "1.12.0-rc.1", false},
+		{"1.12.0-rc.1", "1.12.0-rc.1+build.2", false},
+		{"1.12.0-rc.1", "1.12.0", false},
+		{"1.12.0", "1.12.1-rc.1", false},
+		{"1.12.0-rc.1", "1.12.1-rc.2", false},
+		{"unknown", "1.12.0-rc.1", false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.from+" to "+v.to, func(t *testing.T) {
+			if got := IsPrereleaseBump(BuildInfo{Version: v.from}, BuildInfo{Version: v.to}); got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestFeatureEnabledForVersion(t *testing.T) {
+	const envVar = "ISTIO_TEST_FEATURE_MINVERSION"
+
+	cases := []struct {
+		name       string
+		env        *string
+		current    string
+		expectFail bool
+		want       bool
+	}{
+		{"unset", nil, "1.11.2", false, true},
+		{"empty", strPtr(""), "1.11.2", false, true},
+		{"satisfied", strPtr("1.11"), "1.11.2", false, true},
+		{"equal", strPtr("1.12"), "1.12.0", false, true},
+		{"not satisfied", strPtr("1.12"), "1.11.9", false, false},
+		{"prerelease not satisfied", strPtr("1.12"), "1.12.0-rc.1", false, false},
+		{"unparseable current", strPtr("1.12"), "unknown", false, false},
+		{"malformed", strPtr("latest"), "1.12.0", true, false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if v.env == nil {
+				os.Unsetenv(envVar)
+			} else {
+				os.Setenv(envVar, *v.env)
+			}
+			defer os.Unsetenv(envVar)
+
+			got, err := FeatureEnabledForVersion(envVar, BuildInfo{Version: v.current})
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestVersionInt(t *testing.T) {
+	cases := []struct {
+		version    string
+		expectFail bool
+		want       int64
+	}{
+		{"1.11.2", false, 1011002},
+		{"1.2", false, 1002000},
+		{"0.0.1", false, 1},
+		{"1.12.0-rc.1", false, 1012000},
+		{"12.999.999", false, 12999999},
+		{"1.1000.0", true, 0},
+		{"1.0.1000", true, 0},
+		{"9223372036853.999.999", false, 9223372036853999999},
+		{"9223372036854.0.0", true, 0},
+		{"9300000000000.1.1", true, 0},
+		{"unknown", true, 0},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version, func(t *testing.T) {
+			got, err := BuildInfo{Version: v.version}.VersionInt()
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if got != v.want {
+				t.Errorf("got %d; want %d", got, v.want)
+			}
+		})
+	}
+}
+
+func TestLatest(t *testing.T) {
+	cases := []struct {
+		name       string
+		in         []string
+		expectFail bool
+		want       string
+	}{
+		{"single", []string{"1.11.2"}, false, "1.11.2"},
+		{"ordered", []string{"1.9.0", "1.12.1", "1.11.5"}, false, "1.12.1"},
+		{"release beats prerelease", []string{"1.12.0-rc.1", "1.12.0", "1.12.0-rc.2"}, false, "1.12.0"},
+		{"prerelease beats lower release", []string{"1.12.3", "1.13.0-alpha.1"}, false, "1.13.0-alpha.1"},
+		{"metadata tie", []string{"v1.12.0+a", "1.12.0+b"}, false, "v1.12.0+a"},
+		{"ignores unparseable", []string{"unknown", "1.10.0", "latest"}, false, "1.10.0"},
+		{"none parse", []string{"unknown", ""}, true, ""},
+		{"empty", nil, true, ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := Latest(v.in)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeVersions(t *testing.T) {
+	cases := []struct {
+		name string
+		in   []string
+		want []string
+	}{
+		{"empty", nil, []string{}},
+		{
+			"mixed",
+			[]string{"v1.12.0", "1.11.2", "1.12.0+build.1", "latest", "1.12.0-rc.1", "v1.9.0", "dev", "latest", "1.2", "1.2.0"},
+			[]string{"1.2", "1.2.0", "1.9.0", "1.11.2", "1.12.0-rc.1", "1.12.0", "dev", "latest"},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := NormalizeVersions(v.in); !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %#v; want %#v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestPrereleaseStage(t *testing.T) {
+	cases := []struct {
+		version string
+		want    string
+	}{
+		{"1.12.0-alpha.5d4c7a1b", "alpha"},
+		{"1.12.0-beta.2", "beta"},
+		{"1.12.0-rc.1", "rc"},
+		{"1.12.0-RC1", "rc"},
+		{"1.12.0-dev", "dev"},
+		{"1.12.0-preview.1", "preview.1"},
+		{"1.12.0", ""},
+		{"1.12.0+build.1", ""},
+		{"unknown", ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version, func(t *testing.T) {
+			if got := (BuildInfo{Version: v.version}).PrereleaseStage(); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestUpdateAvailable(t *testing.T) {
+	fetch := func(v string, err error) func() (string, error) {
+		return func() (string, error) { return v, err }
+	}
+
+	cases := []struct {
+		name       string
+		current    string
+		fetch      func() (string, error)
+		expectFail bool
+		want       bool
+		wantMsg    string
+	}{
+		{"update", "1.11.2", fetch("1.12.0", nil), false, true, "version 1.12.0 is available (current version is 1.11.2)"},
+		{"latest", "1.12.0", fetch("1.12.0", nil), false, false, "version 1.12.0 is the latest release"},
+		{"prerelease testing", "1.13.0-rc.1", fetch("1.12.0", nil), false, false,
+			"version 1.13.0-rc.1 is newer than the latest release 1.12.0"},
+		{"fetch error", "1.11.2", fetch("", errors.New("offline")), true, false, ""},
+		{"malformed latest", "1.11.2", fetch("<html>", nil), true, false, ""},
+		{"dev build", "unknown", fetch("1.12.0", nil), true, false, ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, msg, err := UpdateAvailable(BuildInfo{Version: v.current}, v.fetch)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+			if msg != v.wantMsg {
+				t.Errorf("got %q; want %q", msg, v.wantMsg)
+			}
+		})
+	}
+}
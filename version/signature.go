@@ -0,0 +1,48 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"crypto/ed25519"
+	"fmt"
+)
+
+// SignBuildInfo produces an ed25519 signature over the MarshalBinary encoding of info, which
+// is deterministic since Extra entries are sorted by key. It is meant for build systems
+// vouching for the build information they stamp.
+func SignBuildInfo(info BuildInfo, priv ed25519.PrivateKey) ([]byte, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid ed25519 private key length %d", len(priv))
+	}
+	data, err := info.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return ed25519.Sign(priv, data), nil
+}
+
+// VerifyBuildSignature reports whether sig is a valid ed25519 signature by pub over the
+// MarshalBinary encoding of info, as produced by SignBuildInfo. Malformed keys, and build
+// information that cannot be encoded, never verify.
+func VerifyBuildSignature(info BuildInfo, sig []byte, pub ed25519.PublicKey) bool {
+	if len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	data, err := info.MarshalBinary()
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(pub, data, sig)
+}
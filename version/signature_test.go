@@ -0,0 +1,82 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+)
+
+func TestBuildSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{1}, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatalf("Got %v, expected success", err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{2}, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatalf("Got %v, expected success", err)
+	}
+
+	signed := BuildInfo{
+		Version:       "1.11.2",
+		GitRevision:   "3a136c90ec5e308f236e0d7ebb5c4c5e405217f4",
+		GolangVersion: "go1.16.7",
+		BuildStatus:   "Clean",
+		GitTag:        "1.11.2",
+		Extra:         map[string]string{"pipeline": "1234", "builder": "ci"},
+	}
+	sig, err := SignBuildInfo(signed, priv)
+	if err != nil {
+		t.Fatalf("Got %v, expected success", err)
+	}
+
+	tampered := signed
+	tampered.Version = "1.11.3"
+	reordered := signed
+	reordered.Extra = map[string]string{"builder": "ci", "pipeline": "1234"}
+
+	cases := []struct {
+		name string
+		info BuildInfo
+		sig  []byte
+		pub  ed25519.PublicKey
+		want bool
+	}{
+		{"valid", signed, sig, pub, true},
+		{"same extra entries", reordered, sig, pub, true},
+		{"tampered", tampered, sig, pub, false},
+		{"other key", signed, sig, otherPub, false},
+		{"truncated signature", signed, sig[:len(sig)-1], pub, false},
+		{"malformed key", signed, sig, pub[:10], false},
+		{"unencodable", BuildInfo{Version: strings.Repeat("v", 65536)}, sig, pub, false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := VerifyBuildSignature(v.info, v.sig, v.pub); got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+
+	if _, err := SignBuildInfo(signed, priv[:10]); err == nil {
+		t.Errorf("Expected failure, got success")
+	}
+	if _, err := SignBuildInfo(BuildInfo{Version: strings.Repeat("v", 65536)}, priv); err == nil {
+		t.Errorf("Expected failure, got success")
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	supportWindowsMu sync.RWMutex
+	supportWindows   = make(map[string]string)
+)
+
+// RegisterSupportWindow records when a release train, given as a version such as "1.11" or
+// "1.11.2", stops being supported, for use by SupportedUntil. The end of support is free-form,
+// typically the release whose arrival ends support, such as "1.14", or a date. Support windows
+// change over time, so none are registered by default. Registering a train again replaces its
+// window; versions that cannot be parsed are ignored.
+func RegisterSupportWindow(train, until string) {
+	key := BuildInfo{Version: train}.Train()
+	if key == "" {
+		return
+	}
+	supportWindowsMu.Lock()
+	defer supportWindowsMu.Unlock()
+	supportWindows[key] = strings.TrimSpace(until)
+}
+
+// SupportedUntil returns the end of support registered with RegisterSupportWindow for the
+// release train of version, so that "1.11.2" reports the window registered for "1.11". It
+// returns false when the version cannot be parsed or its train has no registered window.
+func SupportedUntil(version string) (string, bool) {
+	train := BuildInfo{Version: version}.Train()
+	if train == "" {
+		return "", false
+	}
+	supportWindowsMu.RLock()
+	defer supportWindowsMu.RUnlock()
+	until, ok := supportWindows[train]
+	return until, ok
+}
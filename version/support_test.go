@@ -0,0 +1,58 @@
+// Copyright 2021 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"testing"
+)
+
+func TestSupportedUntil(t *testing.T) {
+	defer func() {
+		supportWindowsMu.Lock()
+		defer supportWindowsMu.Unlock()
+		supportWindows = make(map[string]string)
+	}()
+	RegisterSupportWindow("1.11", "1.14")
+	RegisterSupportWindow("1.12.3", "2022-10-31")
+	RegisterSupportWindow("latest", "never")
+
+	cases := []struct {
+		version string
+		want    string
+		found   bool
+	}{
+		{"1.11.0", "1.14", true},
+		{"1.11.2", "1.14", true},
+		{"v1.11.2+build.1", "1.14", true},
+		{"1.12.0-rc.1", "2022-10-31", true},
+		{"1.13.0", "", false},
+		{"latest", "", false},
+		{"unknown", "", false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version, func(t *testing.T) {
+			got, found := SupportedUntil(v.version)
+			if got != v.want || found != v.found {
+				t.Errorf("got %q, %v; want %q, %v", got, found, v.want, v.found)
+			}
+		})
+	}
+
+	RegisterSupportWindow("1.11", "1.15")
+	if got, _ := SupportedUntil("1.11.2"); got != "1.15" {
+		t.Errorf("got %q after re-registering; want %q", got, "1.15")
+	}
+}
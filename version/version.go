@@ -16,11 +16,47 @@
 package version
 
 import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
+	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
 )
 
+// productName is the name of the product reported in banners and headers.
+const productName = "Istio"
+
+// DownloadURLTemplate is the URL from which istioctl releases are downloaded. It is expanded
+// with fmt, using the version, the release platform and the archive extension as arguments 1,
+// 2 and 3 respectively. The platform and extension follow the names of Istio's release assets:
+// "linux-amd64" with "tar.gz", "osx" or "osx-arm64" with "tar.gz", and "win" with "zip".
+var DownloadURLTemplate = "https://github.com/istio/istio/releases/download/%[1]s/istioctl-%[1]s-%[2]s.%[3]s"
+
+// DevelopmentBranch is the branch reported by ReleaseBranch for development builds.
+var DevelopmentBranch = "master"
+
+// gitDescribeRegexp matches `git describe` output for commits after a tag: <tag>-<n>-g<revision>
+var gitDescribeRegexp = regexp.MustCompile(`^(.+)-(\d+)-g([0-9a-fA-F]{4,40})$`)
+
+// gitDescribeSuffixRegexp matches the -<n>-g<revision> part of `git describe` output anywhere
+// in a string, to reject shapes that gitDescribeRegexp does not recognize.
+var gitDescribeSuffixRegexp = regexp.MustCompile(`-\d+-g[0-9a-fA-F]{4,40}(-|$)`)
+
+// shortRevisionLength is the length of abbreviated git revisions, matching git's default.
+const shortRevisionLength = 7
+
+// revisionRegexp matches lowercase git revisions at least shortRevisionLength long.
+var revisionRegexp = regexp.MustCompile(`^[0-9a-f]{7,}$`)
+
 // The following fields are populated at build time using -ldflags -X.
 // Note that DATE is omitted for reproducible builds
 var (
@@ -29,6 +65,8 @@ var (
 	buildStatus      = "unknown"
 	buildTag         = "unknown"
 	buildHub         = "unknown"
+	// buildSource is where the binary was built: "ci" for official pipelines, "local" otherwise.
+	buildSource = "local"
 )
 
 // BuildInfo describes version information about the binary build.
@@ -38,6 +76,10 @@ type BuildInfo struct {
 	GolangVersion string `json:"golang_version"`
 	BuildStatus   string `json:"status"`
 	GitTag        string `json:"tag"`
+	// Source is where the binary was built, such as "ci" or "local". Optional.
+	Source string `json:"source,omitempty"`
+	// Extra holds additional, vendor-specific build metadata. Optional.
+	Extra map[string]string `json:"extra,omitempty"`
 }
 
 // ServerInfo contains the version for a single control plane component
@@ -53,6 +95,8 @@ type MeshInfo []ServerInfo
 type ProxyInfo struct {
 	ID           string
 	IstioVersion string
+	// Arch is the CPU architecture the proxy runs on, such as amd64 or arm64. Optional.
+	Arch string `json:"arch,omitempty"`
 }
 
 // DockerBuildInfo contains and exposes Hub: buildHub and Tag: buildVersion
@@ -61,6 +105,43 @@ type DockerBuildInfo struct {
 	Tag string
 }
 
+// ParseGitDescribe decomposes the output of `git describe --tags`. For a commit that is
+// exactly at a tag, such as "1.11.2", it returns the tag with no commits ahead and no revision.
+// For later commits, such as "1.11.2-5-gabc123", it returns the tag, the number of commits
+// ahead of it, and the abbreviated revision. The tag must be a valid semantic version.
+// The "-dirty" suffix added by `git describe --dirty` is ignored.
+func ParseGitDescribe(s string) (base string, commitsAhead int, revision string, err error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "-dirty")
+	if m := gitDescribeRegexp.FindStringSubmatch(s); m != nil {
+		if _, err := parseSemver(m[1]); err == nil {
+			ahead, err := strconv.Atoi(m[2])
+			if err != nil {
+				return "", 0, "", fmt.Errorf("invalid git describe output %q: %v", s, err)
+			}
+			return m[1], ahead, strings.ToLower(m[3]), nil
+		}
+	}
+	if _, err := parseSemver(s); err != nil || gitDescribeSuffixRegexp.MatchString(s) {
+		return "", 0, "", fmt.Errorf("unrecognized git describe output %q", s)
+	}
+	return s, 0, "", nil
+}
+
+// HubsConsistent reports whether all images were pulled from the same Docker hub, along with
+// the distinct hubs in order of appearance. Empty and "unknown" hubs are included as is, so
+// that they remain visible.
+func HubsConsistent(infos []DockerBuildInfo) (bool, []string) {
+	seen := make(map[string]bool)
+	hubs := []string{}
+	for _, info := range infos {
+		if !seen[info.Hub] {
+			seen[info.Hub] = true
+			hubs = append(hubs, info.Hub)
+		}
+	}
+	return len(hubs) <= 1, hubs
+}
+
 // NewBuildInfoFromOldString creates a BuildInfo struct based on the output
 // of previous Istio components '-- version' output
 func NewBuildInfoFromOldString(oldOutput string) (BuildInfo, error) {
@@ -98,6 +179,80 @@ func NewBuildInfoFromOldString(oldOutput string) (BuildInfo, error) {
 	return res, nil
 }
 
+// SplitVersionBlobs reads a stream of concatenated '-- version' outputs, as accepted by
+// NewBuildInfoFromOldString, separated by lines equal to separator (ignoring surrounding
+// whitespace). Blocks holding only whitespace are skipped. Blocks that fail to parse are
+// reported together, identified by their zero-based index in the stream, and the blocks that
+// did parse are still returned.
+func SplitVersionBlobs(r io.Reader, separator string) ([]BuildInfo, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMeshInfoLineSize)
+	separator = strings.TrimSpace(separator)
+
+	var (
+		res   []BuildInfo
+		errs  error
+		block []string
+		index int
+	)
+	flush := func() {
+		text := strings.Join(block, "\n")
+		block = block[:0]
+		if strings.TrimSpace(text) != "" {
+			if info, err := NewBuildInfoFromOldString(text); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("block %d: %v", index, err))
+			} else {
+				res = append(res, info)
+			}
+		}
+		index++
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == separator {
+			flush()
+			continue
+		}
+		block = append(block, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read version blobs: %v", err)
+	}
+	flush()
+
+	return res, errs
+}
+
+// ParseWithPattern creates a BuildInfo from a build string in a custom format, using the
+// named capture groups of re: "version", "revision", "golang_version", "status" and "tag".
+// Fields whose group is absent from re, or did not participate in the match, are left empty;
+// other groups are ignored. It is an error for re not to match s.
+func ParseWithPattern(s string, re *regexp.Regexp) (BuildInfo, error) {
+	if re == nil {
+		return BuildInfo{}, fmt.Errorf("no pattern to parse %q with", s)
+	}
+	m := re.FindStringSubmatch(s)
+	if m == nil {
+		return BuildInfo{}, fmt.Errorf("%q does not match pattern %q", s, re.String())
+	}
+
+	res := BuildInfo{}
+	fields := map[string]*string{
+		"version":        &res.Version,
+		"revision":       &res.GitRevision,
+		"golang_version": &res.GolangVersion,
+		"status":         &res.BuildStatus,
+		"tag":            &res.GitTag,
+	}
+	for i, name := range re.SubexpNames() {
+		if f, ok := fields[name]; ok {
+			*f = m[i]
+		}
+	}
+	return res, nil
+}
+
 var (
 	// Info exports the build version information.
 	Info       BuildInfo
@@ -118,6 +273,29 @@ func (b BuildInfo) String() string {
 		b.BuildStatus)
 }
 
+// StringSep produces the same fields as String, joined by sep instead of a dash, which is
+// ambiguous because versions and revisions may themselves contain dashes. The fields are not
+// escaped, so sep must not occur in them; a space or "|" is a safe choice for Istio builds.
+//
+// This looks like, for sep "|":
+//
+// ```
+// <version>|<git revision>|<build status>
+// ```
+func (b BuildInfo) StringSep(sep string) string {
+	return strings.Join([]string{b.Version, b.GitRevision, b.BuildStatus}, sep)
+}
+
+// ShortString produces only the version, for scripts parsing `version --short` output. A
+// leading "v" and build metadata are removed from valid semantic versions; other versions,
+// such as those of development builds, are returned as is.
+func (b BuildInfo) ShortString() string {
+	if ver, ok := cleanVersion(b.Version); ok {
+		return ver
+	}
+	return b.Version
+}
+
 // LongForm returns a dump of the Info struct
 // This looks like:
 //
@@ -125,6 +303,355 @@ func (b BuildInfo) LongForm() string {
 	return fmt.Sprintf("%#v", b)
 }
 
+// GoString produces the Go-syntax representation of the struct, as used by LongForm.
+// Source is only included when set, and Extra when it holds at least one entry.
+func (b BuildInfo) GoString() string {
+	res := fmt.Sprintf("version.BuildInfo{Version:%q, GitRevision:%q, GolangVersion:%q, BuildStatus:%q, GitTag:%q",
+		b.Version, b.GitRevision, b.GolangVersion, b.BuildStatus, b.GitTag)
+	if b.Source != "" {
+		res += fmt.Sprintf(", Source:%q", b.Source)
+	}
+	if len(b.Extra) > 0 {
+		res += fmt.Sprintf(", Extra:%#v", b.Extra)
+	}
+	return res + "}"
+}
+
+// SetExtra records an additional build field in the package-level Info, so that it is
+// included in all version output. It is safe for concurrent use with Get.
+func SetExtra(key, value string) {
+	mu.Lock()
+	defer mu.Unlock()
+	// copy on write, so that BuildInfo values previously returned by Get remain unchanged
+	extra := make(map[string]string, len(Info.Extra)+1)
+	for k, v := range Info.Extra {
+		extra[k] = v
+	}
+	extra[key] = value
+	Info.Extra = extra
+}
+
+// Normalize returns a copy of the build information in canonical form: surrounding
+// whitespace is trimmed from all fields, GitRevision is lowercased, and a leading "v"
+// is removed from Version when it is a valid semantic version.
+func (b BuildInfo) Normalize() BuildInfo {
+	b.Version = strings.TrimSpace(b.Version)
+	if _, err := parseSemver(b.Version); err == nil {
+		b.Version = strings.TrimPrefix(b.Version, "v")
+	}
+	b.GitRevision = strings.ToLower(strings.TrimSpace(b.GitRevision))
+	b.GolangVersion = strings.TrimSpace(b.GolangVersion)
+	b.BuildStatus = strings.TrimSpace(b.BuildStatus)
+	b.GitTag = strings.TrimSpace(b.GitTag)
+	return b
+}
+
+// CacheBuster returns a short, URL-safe value identifying the build, suitable for appending
+// to asset URLs as `?v=<value>`. It is the abbreviated GitRevision, or the Version when the
+// revision is unknown.
+func (b BuildInfo) CacheBuster() string {
+	if rev := shortRevision(b.GitRevision); rev != "" {
+		return rev
+	}
+	return url.QueryEscape(strings.TrimSpace(b.Version))
+}
+
+// shortRevision returns the abbreviated, lowercased form of a git revision, or an empty
+// string if the revision is unknown.
+func shortRevision(rev string) string {
+	rev = strings.ToLower(strings.TrimSpace(rev))
+	if rev == "unknown" {
+		return ""
+	}
+	if len(rev) > shortRevisionLength {
+		rev = rev[:shortRevisionLength]
+	}
+	return rev
+}
+
+// Hash returns a short hexadecimal digest identifying the build: the first 8 bytes of the
+// SHA-256 of LongForm. Any change to the build information changes the hash.
+func (b BuildInfo) Hash() string {
+	sum := sha256.Sum256([]byte(b.LongForm()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ETag returns a strong HTTP entity tag derived from Hash, including the surrounding quotes
+// required by RFC 7232, such as `"3a5f0c1e9b2d4a67"`.
+func (b BuildInfo) ETag() string {
+	return `"` + b.Hash() + `"`
+}
+
+// JSONIndent returns the build information as JSON indented with two spaces.
+// Fields are always emitted in the same order as declared in BuildInfo, which
+// makes the output suitable for golden-file comparisons.
+func (b BuildInfo) JSONIndent() (string, error) {
+	out, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// JSONOmitUnknown returns the build information as JSON, omitting fields that are empty or
+// still set to "unknown", as is the case for development builds. The default JSON encoding
+// keeps emitting "unknown" so that missing build information remains visible.
+func (b BuildInfo) JSONOmitUnknown() (string, error) {
+	known := func(s string) string {
+		if s == "unknown" {
+			return ""
+		}
+		return s
+	}
+	out, err := json.Marshal(buildInfoOmitEmpty{
+		Version:       known(b.Version),
+		GitRevision:   known(b.GitRevision),
+		GolangVersion: known(b.GolangVersion),
+		BuildStatus:   known(b.BuildStatus),
+		GitTag:        known(b.GitTag),
+		Source:        known(b.Source),
+		Extra:         b.Extra,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// buildInfoOmitEmpty mirrors BuildInfo, omitting empty fields from JSON.
+type buildInfoOmitEmpty struct {
+	Version       string            `json:"version,omitempty"`
+	GitRevision   string            `json:"revision,omitempty"`
+	GolangVersion string            `json:"golang_version,omitempty"`
+	BuildStatus   string            `json:"status,omitempty"`
+	GitTag        string            `json:"tag,omitempty"`
+	Source        string            `json:"source,omitempty"`
+	Extra         map[string]string `json:"extra,omitempty"`
+}
+
+// MarshalOptions selects fields to omit from MarshalJSONWith output.
+type MarshalOptions struct {
+	OmitRevision      bool
+	OmitStatus        bool
+	OmitGolangVersion bool
+}
+
+// MarshalJSONWith returns the build information as JSON, omitting the fields selected by opts.
+// The zero MarshalOptions produces the same output as json.Marshal.
+func (b BuildInfo) MarshalJSONWith(opts MarshalOptions) ([]byte, error) {
+	keep := func(s string, omit bool) *string {
+		if omit {
+			return nil
+		}
+		return &s
+	}
+	return json.Marshal(buildInfoOptionalFields{
+		Version:       b.Version,
+		GitRevision:   keep(b.GitRevision, opts.OmitRevision),
+		GolangVersion: keep(b.GolangVersion, opts.OmitGolangVersion),
+		BuildStatus:   keep(b.BuildStatus, opts.OmitStatus),
+		GitTag:        b.GitTag,
+		Source:        b.Source,
+		Extra:         b.Extra,
+	})
+}
+
+// buildInfoOptionalFields mirrors BuildInfo, omitting the fields left nil from JSON.
+type buildInfoOptionalFields struct {
+	Version       string            `json:"version"`
+	GitRevision   *string           `json:"revision,omitempty"`
+	GolangVersion *string           `json:"golang_version,omitempty"`
+	BuildStatus   *string           `json:"status,omitempty"`
+	GitTag        string            `json:"tag"`
+	Source        string            `json:"source,omitempty"`
+	Extra         map[string]string `json:"extra,omitempty"`
+}
+
+// UnmarshalJSON decodes build information written with either the current snake_case keys
+// (golang_version) or the camelCase keys (golangVersion) emitted by some older producers.
+// Keys are matched case-insensitively and, when both spellings are present, the snake_case
+// one wins. Unknown keys are ignored.
+func (b *BuildInfo) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	targets := map[string]interface{}{
+		"version":       &b.Version,
+		"revision":      &b.GitRevision,
+		"golangversion": &b.GolangVersion,
+		"status":        &b.BuildStatus,
+		"tag":           &b.GitTag,
+		"source":        &b.Source,
+		"extra":         &b.Extra,
+	}
+
+	// decode snake_case keys last, so that they take precedence
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		iSnake, jSnake := strings.Contains(keys[i], "_"), strings.Contains(keys[j], "_")
+		if iSnake != jSnake {
+			return jSnake
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		target, ok := targets[strings.ToLower(strings.Replace(key, "_", "", -1))]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(fields[key], target); err != nil {
+			return fmt.Errorf("invalid %q in build info: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// HelmAppVersion returns the version in the form expected by the appVersion field of
+// a Helm Chart.yaml: without a leading "v" and without build metadata. Development
+// builds, whose version is not a valid semantic version, produce an empty string.
+func (b BuildInfo) HelmAppVersion() string {
+	ver, _ := cleanVersion(b.Version)
+	return ver
+}
+
+// DownloadURL returns the URL of the istioctl release matching the build's version and the
+// running OS and architecture, expanded from DownloadURLTemplate. Development builds, whose
+// version is not a valid semantic version, produce an empty string.
+func (b BuildInfo) DownloadURL() string {
+	return b.downloadURL(runtime.GOOS, runtime.GOARCH)
+}
+
+func (b BuildInfo) downloadURL(goos, goarch string) string {
+	ver, ok := cleanVersion(b.Version)
+	if !ok {
+		return ""
+	}
+	platform, ext := releasePlatform(goos, goarch)
+	return fmt.Sprintf(DownloadURLTemplate, ver, platform, ext)
+}
+
+// releasePlatform returns the platform and archive extension used in the names of istioctl
+// release assets, which differ from GOOS and GOARCH on macOS and Windows: amd64 builds carry
+// no architecture suffix there, and Windows releases are zip archives.
+func releasePlatform(goos, goarch string) (string, string) {
+	switch goos {
+	case "darwin":
+		if goarch == "amd64" {
+			return "osx", "tar.gz"
+		}
+		return "osx-" + goarch, "tar.gz"
+	case "windows":
+		if goarch == "amd64" {
+			return "win", "zip"
+		}
+		return "win-" + goarch, "zip"
+	}
+	return goos + "-" + goarch, "tar.gz"
+}
+
+// ArtifactName returns the conventional file name of a release artifact built for the running
+// OS and architecture:
+//
+// ```
+// <tool>-<version>-<GOOS>-<GOARCH>.<ext>
+// ```
+//
+// such as `istioctl-1.11.2-linux-amd64.tar.gz`. The version has any leading "v" and build
+// metadata removed; development builds, whose version is not a valid semantic version, use
+// "dev" instead. A leading "." in ext is optional, and an empty ext produces no extension.
+func (b BuildInfo) ArtifactName(tool, ext string) string {
+	return b.artifactName(tool, ext, runtime.GOOS, runtime.GOARCH)
+}
+
+func (b BuildInfo) artifactName(tool, ext, goos, goarch string) string {
+	ver, ok := cleanVersion(b.Version)
+	if !ok {
+		ver = "dev"
+	}
+	name := fmt.Sprintf("%s-%s-%s-%s", tool, ver, goos, goarch)
+	if ext = strings.TrimPrefix(ext, "."); ext != "" {
+		name += "." + ext
+	}
+	return name
+}
+
+// RevisionIn reports whether the build's GitRevision is in the allowed list.
+// Revisions are compared case-insensitively and by prefix, so that an allowed abbreviated
+// revision such as "3a136c9" matches the full 40-character revision it was derived from; an
+// abbreviated build revision never matches a longer allowed one. Both the build revision and
+// the allowed entries must be hex strings of at least 7 characters; other entries are ignored.
+func (b BuildInfo) RevisionIn(allowed []string) bool {
+	revision := b.Normalize().GitRevision
+	if !revisionRegexp.MatchString(revision) {
+		return false
+	}
+	for _, rev := range allowed {
+		rev = strings.ToLower(strings.TrimSpace(rev))
+		if revisionRegexp.MatchString(rev) && strings.HasPrefix(revision, rev) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCIBuild reports whether the binary was built by a CI pipeline, as recorded in Source
+// from the buildSource ldflag. Pipelines set it to "ci"; unstamped builds are "local".
+func (b BuildInfo) IsCIBuild() bool {
+	return strings.EqualFold(strings.TrimSpace(b.Source), "ci")
+}
+
+// SelfCheck verifies that the values stamped into the build are consistent with each other:
+// the package-level DockerInfo.Tag and the GitTag, when set and not "unknown", must match
+// Version. A GitTag in `git describe` form is compared by its base tag. Versions are compared
+// ignoring a leading "v" and build metadata. All inconsistencies found are reported together.
+// Use SelfCheckWith to check against the image information of another component.
+func (b BuildInfo) SelfCheck() error {
+	mu.RLock()
+	docker := DockerInfo
+	mu.RUnlock()
+	return b.SelfCheckWith(docker)
+}
+
+// SelfCheckWith is like SelfCheck, but compares against the given image information instead
+// of the package-level DockerInfo.
+func (b BuildInfo) SelfCheckWith(docker DockerBuildInfo) error {
+	var err error
+	if tag := docker.Tag; isKnown(tag) && !sameVersion(tag, b.Version) {
+		err = multierror.Append(err, fmt.Errorf("docker tag %q does not match version %q", tag, b.Version))
+	}
+	if tag := b.GitTag; isKnown(tag) {
+		base, _, _, perr := ParseGitDescribe(tag)
+		if perr != nil {
+			base = tag
+		}
+		if !sameVersion(base, b.Version) {
+			err = multierror.Append(err, fmt.Errorf("git tag %q does not match version %q", tag, b.Version))
+		}
+	}
+	return err
+}
+
+func isKnown(s string) bool {
+	return s != "" && s != "unknown"
+}
+
+// sameVersion reports whether a and b denote the same version, comparing them as semantic
+// versions when both parse, and as strings otherwise.
+func sameVersion(a, b string) bool {
+	x, xerr := parseSemver(a)
+	y, yerr := parseSemver(b)
+	if xerr != nil || yerr != nil {
+		return a == b
+	}
+	return x.compare(y) == 0
+}
+
 func init() {
 	Info = BuildInfo{
 		Version:       buildVersion,
@@ -132,6 +659,7 @@ func init() {
 		GolangVersion: runtime.Version(),
 		BuildStatus:   buildStatus,
 		GitTag:        buildTag,
+		Source:        buildSource,
 	}
 
 	DockerInfo = DockerBuildInfo{
@@ -15,8 +15,12 @@
 package version
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"regexp"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -88,16 +92,108 @@ GitTag: tag
 				t.Errorf("Got %v, expected success", err)
 			}
 
-			if got != v.want {
+			if !reflect.DeepEqual(got, v.want) {
 				t.Errorf("Got %v, expected %v", got, v.want)
 			}
 		})
 	}
 }
 
+func TestParseWithPattern(t *testing.T) {
+	pattern := regexp.MustCompile(`^acme-(?P<version>[0-9.]+)(?:\+(?P<revision>[0-9a-f]+))?(?: \[(?P<status>\w+)\])?(?: (?P<builder>\S+))?$`)
+
+	cases := []struct {
+		name       string
+		in         string
+		re         *regexp.Regexp
+		expectFail bool
+		want       BuildInfo
+	}{
+		{"all groups", "acme-1.11.2+abc123 [Clean]", pattern, false, BuildInfo{Version: "1.11.2", GitRevision: "abc123", BuildStatus: "Clean"}},
+		{"optional groups missing", "acme-1.11.2", pattern, false, BuildInfo{Version: "1.11.2"}},
+		{"unknown groups ignored", "acme-1.11.2 [Clean] ci", pattern, false, BuildInfo{Version: "1.11.2", BuildStatus: "Clean"}},
+		{
+			"tag and golang version",
+			"1.11.2 built with go1.16.7",
+			regexp.MustCompile(`(?P<tag>\S+) built with (?P<golang_version>\S+)`),
+			false,
+			BuildInfo{GitTag: "1.11.2", GolangVersion: "go1.16.7"},
+		},
+		{"no match", "istio-1.11.2", pattern, true, BuildInfo{}},
+		{"nil pattern", "acme-1.11.2", nil, true, BuildInfo{}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := ParseWithPattern(v.in, v.re)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %#v; want %#v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestSplitVersionBlobs(t *testing.T) {
+	blob := "Version: 1.0.0\nGitRevision: abc\nBuildStatus: Clean\n"
+	other := "Version: 1.1.0\nGitTag: 1.1.0\n"
+
+	cases := []struct {
+		name       string
+		in         string
+		expectFail bool
+		want       []BuildInfo
+	}{
+		{"empty", "", false, nil},
+		{"single", blob, false, []BuildInfo{{Version: "1.0.0", GitRevision: "abc", BuildStatus: "Clean"}}},
+		{
+			"multiple",
+			blob + "---\n" + other,
+			false,
+			[]BuildInfo{{Version: "1.0.0", GitRevision: "abc", BuildStatus: "Clean"}, {Version: "1.1.0", GitTag: "1.1.0"}},
+		},
+		{
+			"empty blocks",
+			"---\n" + blob + "  ---  \n\n---\n" + other + "---\n",
+			false,
+			[]BuildInfo{{Version: "1.0.0", GitRevision: "abc", BuildStatus: "Clean"}, {Version: "1.1.0", GitTag: "1.1.0"}},
+		},
+		{
+			"invalid block",
+			blob + "---\nXuxa\n---\n" + other,
+			true,
+			[]BuildInfo{{Version: "1.0.0", GitRevision: "abc", BuildStatus: "Clean"}, {Version: "1.1.0", GitTag: "1.1.0"}},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := SplitVersionBlobs(strings.NewReader(v.in), "---")
+			if v.expectFail {
+				if err == nil {
+					t.Fatal("Expected failure, got success")
+				}
+				if !strings.Contains(err.Error(), "block 1:") {
+					t.Errorf("got %q; want the failing block index", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
 func TestBuildInfo(t *testing.T) {
 	versionedString := fmt.Sprintf(`version.BuildInfo{Version:"unknown", GitRevision:"unknown", `+
-		`GolangVersion:"%s", BuildStatus:"unknown", GitTag:"unknown"}`,
+		`GolangVersion:"%s", BuildStatus:"unknown", GitTag:"unknown", Source:"local"}`,
 		runtime.Version())
 
 	cases := []struct {
@@ -120,6 +216,36 @@ func TestBuildInfo(t *testing.T) {
 				`BuildStatus:"STATUS", GitTag:"TAG"}`,
 		},
 
+		{
+			"extra",
+			BuildInfo{
+				Version:       "VER",
+				GitRevision:   "GITREV",
+				GolangVersion: "GOLANGVER",
+				BuildStatus:   "STATUS",
+				GitTag:        "TAG",
+				Extra:         map[string]string{"pipeline": "1234", "ticket": "ABC-1"},
+			},
+			"VER-GITREV-STATUS",
+			`version.BuildInfo{Version:"VER", GitRevision:"GITREV", GolangVersion:"GOLANGVER", ` +
+				`BuildStatus:"STATUS", GitTag:"TAG", Extra:map[string]string{"pipeline":"1234", "ticket":"ABC-1"}}`,
+		},
+
+		{
+			"source",
+			BuildInfo{
+				Version:       "VER",
+				GitRevision:   "GITREV",
+				GolangVersion: "GOLANGVER",
+				BuildStatus:   "STATUS",
+				GitTag:        "TAG",
+				Source:        "ci",
+			},
+			"VER-GITREV-STATUS",
+			`version.BuildInfo{Version:"VER", GitRevision:"GITREV", GolangVersion:"GOLANGVER", ` +
+				`BuildStatus:"STATUS", GitTag:"TAG", Source:"ci"}`,
+		},
+
 		{"init", Info, "unknown-unknown-unknown", versionedString},
 	}
 
@@ -135,3 +261,582 @@ func TestBuildInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestStringSep(t *testing.T) {
+	in := BuildInfo{Version: "1.12.0-rc.1", GitRevision: "abc123", BuildStatus: "Clean"}
+	cases := []struct {
+		sep  string
+		want string
+	}{
+		{"-", in.String()},
+		{"|", "1.12.0-rc.1|abc123|Clean"},
+		{" ", "1.12.0-rc.1 abc123 Clean"},
+		{"", "1.12.0-rc.1abc123Clean"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.sep, func(t *testing.T) {
+			if got := in.StringSep(v.sep); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestShortString(t *testing.T) {
+	cases := []struct {
+		version string
+		want    string
+	}{
+		{"1.11.2", "1.11.2"},
+		{"v1.12.0-rc.1+build.5", "1.12.0-rc.1"},
+		{" 1.11.2 ", "1.11.2"},
+		{"unknown", "unknown"},
+		{"1.12-dev", "1.12-dev"},
+		{"", ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version, func(t *testing.T) {
+			in := BuildInfo{Version: v.version, GitRevision: "abc123", BuildStatus: "Clean"}
+			if got := in.ShortString(); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestRevisionIn(t *testing.T) {
+	full := "3a136c90ec5e308f236e0d7ebb5c4c5e405217f4"
+	cases := []struct {
+		name     string
+		revision string
+		allowed  []string
+		want     bool
+	}{
+		{"full match", full, []string{"deadbeef", full}, true},
+		{"short allowed", full, []string{"3a136c9"}, true},
+		{"short revision", "3a136c9", []string{full}, false},
+		{"one character revision", "3", []string{full}, false},
+		{"one character allowed", full, []string{"3"}, false},
+		{"six character allowed", full, []string{"3a136c"}, false},
+		{"non-hex allowed", "3a136c9zzz", []string{"3a136c9"}, false},
+		{"no match", full, []string{"3a136c8", "deadbeef"}, false},
+		{"uppercase revision", strings.ToUpper(full), []string{"3a136c9"}, true},
+		{"uppercase allowed", full, []string{"3A136C9"}, true},
+		{"empty allowed entry", full, []string{""}, false},
+		{"nil allowed", full, nil, false},
+		{"unknown revision", "unknown", []string{"unknown"}, false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := (BuildInfo{GitRevision: v.revision}).RevisionIn(v.allowed); got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestJSONIndent(t *testing.T) {
+	in := BuildInfo{Version: "VER", GitRevision: "GITREV", GolangVersion: "GOLANGVER", BuildStatus: "STATUS", GitTag: "TAG"}
+	want := `{
+  "version": "VER",
+  "revision": "GITREV",
+  "golang_version": "GOLANGVER",
+  "status": "STATUS",
+  "tag": "TAG"
+}`
+
+	for i := 0; i < 3; i++ {
+		got, err := in.JSONIndent()
+		if err != nil {
+			t.Fatalf("Got %v, expected success", err)
+		}
+		if got != want {
+			t.Errorf("got\n%s\nwant\n%s", got, want)
+		}
+	}
+}
+
+func TestSetExtra(t *testing.T) {
+	defer Snapshot()()
+	Info.Extra = nil
+
+	SetExtra("pipeline", "1234")
+	if got, _ := Info.JSONIndent(); !strings.Contains(got, `"extra": {
+    "pipeline": "1234"
+  }`) {
+		t.Errorf("extra field missing from JSON output:\n%s", got)
+	}
+
+	Info.Extra = nil
+	if got, _ := Info.JSONIndent(); strings.Contains(got, "extra") {
+		t.Errorf("empty extra field present in JSON output:\n%s", got)
+	}
+}
+
+func TestHelmAppVersion(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"1.11.2", "1.11.2"},
+		{"v1.11.2", "1.11.2"},
+		{"1.12.0-rc.1+build.5", "1.12.0-rc.1"},
+		{"1.2", "1.2"},
+		{"unknown", ""},
+		{"", ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.in, func(t *testing.T) {
+			if got := (BuildInfo{Version: v.in}).HelmAppVersion(); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestJSONOmitUnknown(t *testing.T) {
+	cases := []struct {
+		name string
+		in   BuildInfo
+		want string
+	}{
+		{
+			"all specified",
+			BuildInfo{Version: "VER", GitRevision: "GITREV", GolangVersion: "GOLANGVER", BuildStatus: "STATUS", GitTag: "TAG"},
+			`{"version":"VER","revision":"GITREV","golang_version":"GOLANGVER","status":"STATUS","tag":"TAG"}`,
+		},
+		{
+			"dev build",
+			BuildInfo{Version: "unknown", GitRevision: "unknown", GolangVersion: "go1.16", BuildStatus: "unknown", GitTag: "unknown"},
+			`{"golang_version":"go1.16"}`,
+		},
+		{
+			"empty and extra",
+			BuildInfo{Version: "VER", GitTag: "unknown", Extra: map[string]string{"pipeline": "1234"}},
+			`{"version":"VER","extra":{"pipeline":"1234"}}`,
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := v.in.JSONOmitUnknown()
+			if err != nil {
+				t.Fatalf("Got %v, expected success", err)
+			}
+			if got != v.want {
+				t.Errorf("got %s; want %s", got, v.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name string
+		in   BuildInfo
+		want BuildInfo
+	}{
+		{
+			"mixed case revision",
+			BuildInfo{Version: "v1.11.2", GitRevision: " 3A136C90ec5e ", GolangVersion: "go1.16", BuildStatus: "Clean ", GitTag: "1.11.2"},
+			BuildInfo{Version: "1.11.2", GitRevision: "3a136c90ec5e", GolangVersion: "go1.16", BuildStatus: "Clean", GitTag: "1.11.2"},
+		},
+		{
+			"unknown",
+			BuildInfo{Version: "unknown", GitRevision: "unknown", BuildStatus: "unknown", GitTag: "unknown"},
+			BuildInfo{Version: "unknown", GitRevision: "unknown", BuildStatus: "unknown", GitTag: "unknown"},
+		},
+		{
+			"non semver version keeps prefix",
+			BuildInfo{Version: "vendor-build"},
+			BuildInfo{Version: "vendor-build"},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.in.Normalize(); !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %#v; want %#v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestCacheBuster(t *testing.T) {
+	cases := []struct {
+		name string
+		in   BuildInfo
+		want string
+	}{
+		{"full revision", BuildInfo{Version: "1.11.2", GitRevision: "3A136C90ec5e308f236e0d7ebb5c4c5e405217f4"}, "3a136c9"},
+		{"short revision", BuildInfo{Version: "1.11.2", GitRevision: "abc12"}, "abc12"},
+		{"unknown revision", BuildInfo{Version: "1.11.2+build 1", GitRevision: "unknown"}, "1.11.2%2Bbuild+1"},
+		{"no revision", BuildInfo{Version: "1.11.2"}, "1.11.2"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.in.CacheBuster(); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestDownloadURL(t *testing.T) {
+	cases := []struct {
+		version string
+		goos    string
+		goarch  string
+		want    string
+	}{
+		{"1.11.2", "linux", "amd64", "https://github.com/istio/istio/releases/download/1.11.2/istioctl-1.11.2-linux-amd64.tar.gz"},
+		{"v1.12.0-rc.1+build.5", "darwin", "arm64",
+			"https://github.com/istio/istio/releases/download/1.12.0-rc.1/istioctl-1.12.0-rc.1-osx-arm64.tar.gz"},
+		{"1.11.2", "darwin", "amd64", "https://github.com/istio/istio/releases/download/1.11.2/istioctl-1.11.2-osx.tar.gz"},
+		{"1.11.2", "windows", "amd64", "https://github.com/istio/istio/releases/download/1.11.2/istioctl-1.11.2-win.zip"},
+		{"unknown", "linux", "amd64", ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.version+" "+v.goos+"/"+v.goarch, func(t *testing.T) {
+			if got := (BuildInfo{Version: v.version}).downloadURL(v.goos, v.goarch); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+
+	platform, ext := releasePlatform(runtime.GOOS, runtime.GOARCH)
+	want := fmt.Sprintf("istioctl-1.11.2-%s.%s", platform, ext)
+	if got := (BuildInfo{Version: "1.11.2"}).DownloadURL(); !strings.HasSuffix(got, want) {
+		t.Errorf("got %q; want suffix %q", got, want)
+	}
+}
+
+func TestDownloadURLTemplate(t *testing.T) {
+	defer func(template string) { DownloadURLTemplate = template }(DownloadURLTemplate)
+	DownloadURLTemplate = "https://mirror.example.com/%[1]s/istioctl-%[2]s.%[3]s"
+
+	want := "https://mirror.example.com/1.11.2/istioctl-win.zip"
+	if got := (BuildInfo{Version: "1.11.2"}).downloadURL("windows", "amd64"); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestArtifactName(t *testing.T) {
+	cases := []struct {
+		version string
+		tool    string
+		ext     string
+		goos    string
+		goarch  string
+		want    string
+	}{
+		{"1.11.2", "istioctl", "tar.gz", "linux", "amd64", "istioctl-1.11.2-linux-amd64.tar.gz"},
+		{"v1.12.0-rc.1+build.5", "istioctl", ".zip", "windows", "amd64", "istioctl-1.12.0-rc.1-windows-amd64.zip"},
+		{"1.11.2", "pilot-discovery", "", "darwin", "arm64", "pilot-discovery-1.11.2-darwin-arm64"},
+		{"unknown", "istioctl", "tar.gz", "linux", "arm64", "istioctl-dev-linux-arm64.tar.gz"},
+	}
+
+	for _, v := range cases {
+		t.Run(v.want, func(t *testing.T) {
+			if got := (BuildInfo{Version: v.version}).artifactName(v.tool, v.ext, v.goos, v.goarch); got != v.want {
+				t.Errorf("got %q; want %q", got, v.want)
+			}
+		})
+	}
+
+	platform, ext := releasePlatform(runtime.GOOS, runtime.GOARCH)
+	want := fmt.Sprintf("istioctl-1.11.2-%s.%s", platform, ext)
+	if got := (BuildInfo{Version: "1.11.2"}).ArtifactName("istioctl", "tar.gz"); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestHubsConsistent(t *testing.T) {
+	cases := []struct {
+		name     string
+		in       []DockerBuildInfo
+		wantOK   bool
+		wantHubs []string
+	}{
+		{"none", nil, true, []string{}},
+		{"same", []DockerBuildInfo{{Hub: "docker.io/istio", Tag: "1.11.2"}, {Hub: "docker.io/istio", Tag: "1.11.1"}}, true,
+			[]string{"docker.io/istio"}},
+		{"mixed", []DockerBuildInfo{{Hub: "gcr.io/istio-release"}, {Hub: "docker.io/istio"}, {Hub: "gcr.io/istio-release"}}, false,
+			[]string{"gcr.io/istio-release", "docker.io/istio"}},
+		{"unknown", []DockerBuildInfo{{Hub: "docker.io/istio"}, {Hub: "unknown"}, {Hub: ""}}, false,
+			[]string{"docker.io/istio", "unknown", ""}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			ok, hubs := HubsConsistent(v.in)
+			if ok != v.wantOK {
+				t.Errorf("got %v; want %v", ok, v.wantOK)
+			}
+			if !reflect.DeepEqual(hubs, v.wantHubs) {
+				t.Errorf("got %q; want %q", hubs, v.wantHubs)
+			}
+		})
+	}
+}
+
+func TestParseGitDescribe(t *testing.T) {
+	cases := []struct {
+		in         string
+		expectFail bool
+		base       string
+		ahead      int
+		revision   string
+	}{
+		{"1.11.2", false, "1.11.2", 0, ""},
+		{"1.11.2-5-gabc123", false, "1.11.2", 5, "abc123"},
+		{"1.12.0-rc.1-12-gABC123D", false, "1.12.0-rc.1", 12, "abc123d"},
+		{"v1.11.2\n", false, "v1.11.2", 0, ""},
+		{"1.11.2-5-gabc1234-dirty", false, "1.11.2", 5, "abc1234"},
+		{"1.11.2-dirty", false, "1.11.2", 0, ""},
+		{"1.11.2-5-gabc1234-broken", true, "", 0, ""},
+		{"abc123", true, "", 0, ""},
+		{"release-1.11-5-gabc123", true, "", 0, ""},
+		{"", true, "", 0, ""},
+	}
+
+	for _, v := range cases {
+		t.Run(v.in, func(t *testing.T) {
+			base, ahead, revision, err := ParseGitDescribe(v.in)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if base != v.base || ahead != v.ahead || revision != v.revision {
+				t.Errorf("got (%q, %d, %q); want (%q, %d, %q)", base, ahead, revision, v.base, v.ahead, v.revision)
+			}
+		})
+	}
+}
+
+func TestETag(t *testing.T) {
+	a := BuildInfo{Version: "1.11.2", GitRevision: "abc123", BuildStatus: "Clean"}
+	b := BuildInfo{Version: "1.11.2", GitRevision: "abc123", BuildStatus: "Modified"}
+	c := BuildInfo{Version: "1.11.2", GitRevision: "abc123", BuildStatus: "Clean", Extra: map[string]string{"k": "v"}}
+
+	etag := a.ETag()
+	if !regexp.MustCompile(`^"[0-9a-f]{16}"$`).MatchString(etag) {
+		t.Errorf("got %s; want a quoted 16 character hex string", etag)
+	}
+	if etag != `"`+a.Hash()+`"` {
+		t.Errorf("got %s; want quoted hash %s", etag, a.Hash())
+	}
+	if a.ETag() != etag {
+		t.Errorf("ETag not stable: %s != %s", a.ETag(), etag)
+	}
+	if b.ETag() == etag || c.ETag() == etag {
+		t.Errorf("ETag did not change with the build: %s, %s, %s", etag, b.ETag(), c.ETag())
+	}
+}
+
+func TestMarshalJSONWith(t *testing.T) {
+	for _, in := range []BuildInfo{
+		{Version: "VER", GitRevision: "GITREV", GolangVersion: "GOLANGVER", BuildStatus: "STATUS", GitTag: "TAG"},
+		{Version: "VER", Extra: map[string]string{"pipeline": "1234"}},
+	} {
+		for mask := 0; mask < 8; mask++ {
+			opts := MarshalOptions{
+				OmitRevision:      mask&1 != 0,
+				OmitStatus:        mask&2 != 0,
+				OmitGolangVersion: mask&4 != 0,
+			}
+			t.Run(fmt.Sprintf("%s %+v", in.Version, opts), func(t *testing.T) {
+				got, err := in.MarshalJSONWith(opts)
+				if err != nil {
+					t.Fatalf("Got %v, expected success", err)
+				}
+
+				var fields map[string]interface{}
+				if err := json.Unmarshal(got, &fields); err != nil {
+					t.Fatalf("Got %v, expected success", err)
+				}
+				for key, omitted := range map[string]bool{
+					"version":        false,
+					"revision":       opts.OmitRevision,
+					"status":         opts.OmitStatus,
+					"golang_version": opts.OmitGolangVersion,
+					"tag":            false,
+				} {
+					if _, present := fields[key]; present == omitted {
+						t.Errorf("field %q present=%v in %s", key, present, got)
+					}
+				}
+
+				if opts == (MarshalOptions{}) {
+					if want, _ := json.Marshal(in); string(got) != string(want) {
+						t.Errorf("got %s; want %s", got, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	full := BuildInfo{
+		Version:       "1.11.2",
+		GitRevision:   "abc123",
+		GolangVersion: "go1.16.7",
+		BuildStatus:   "Clean",
+		GitTag:        "1.11.2",
+	}
+
+	cases := []struct {
+		name       string
+		in         string
+		expectFail bool
+		want       BuildInfo
+	}{
+		{
+			"snake case",
+			`{"version":"1.11.2","revision":"abc123","golang_version":"go1.16.7","status":"Clean","tag":"1.11.2"}`,
+			false,
+			full,
+		},
+		{
+			"camel case",
+			`{"version":"1.11.2","revision":"abc123","golangVersion":"go1.16.7","status":"Clean","tag":"1.11.2"}`,
+			false,
+			full,
+		},
+		{
+			"snake case wins",
+			`{"golang_version":"go1.16.7","golangVersion":"go1.15"}`,
+			false,
+			BuildInfo{GolangVersion: "go1.16.7"},
+		},
+		{
+			"case insensitive",
+			`{"Version":"1.11.2","GolangVersion":"go1.16.7"}`,
+			false,
+			BuildInfo{Version: "1.11.2", GolangVersion: "go1.16.7"},
+		},
+		{
+			"extra and unknown keys",
+			`{"version":"1.11.2","extra":{"pipeline":"1234"},"builder":"ci"}`,
+			false,
+			BuildInfo{Version: "1.11.2", Extra: map[string]string{"pipeline": "1234"}},
+		},
+		{"wrong type", `{"version":1}`, true, BuildInfo{}},
+		{"not an object", `"1.11.2"`, true, BuildInfo{}},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			var got BuildInfo
+			err := json.Unmarshal([]byte(v.in), &got)
+			if v.expectFail && err == nil {
+				t.Errorf("Expected failure, got success")
+			}
+			if !v.expectFail && err != nil {
+				t.Errorf("Got %v, expected success", err)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %#v; want %#v", got, v.want)
+			}
+		})
+	}
+
+	out, _ := json.Marshal(full)
+	var roundTrip BuildInfo
+	if err := json.Unmarshal(out, &roundTrip); err != nil || !reflect.DeepEqual(roundTrip, full) {
+		t.Errorf("got %#v, %v; want %#v", roundTrip, err, full)
+	}
+}
+
+func TestSelfCheck(t *testing.T) {
+	defer Snapshot()()
+
+	cases := []struct {
+		name       string
+		version    string
+		gitTag     string
+		dockerTag  string
+		wantErrors []string
+	}{
+		{"consistent", "1.11.2", "1.11.2", "1.11.2", nil},
+		{"v prefix", "1.11.2", "v1.11.2", "1.11.2", nil},
+		{"git describe", "1.11.2", "1.11.2-5-gabc123", "1.11.2", nil},
+		{"unset tags", "1.11.2", "unknown", "", nil},
+		{"development build", "unknown", "unknown", "unknown", nil},
+		{"docker tag mismatch", "1.11.2", "1.11.2", "1.11.1", []string{`docker tag "1.11.1" does not match version "1.11.2"`}},
+		{"git tag mismatch", "1.11.2", "1.12.0-rc.1", "1.11.2", []string{`git tag "1.12.0-rc.1" does not match version "1.11.2"`}},
+		{"non-semver tag", "1.11.2", "release", "1.11.2", []string{`git tag "release" does not match version "1.11.2"`}},
+		{
+			"both mismatched",
+			"unknown",
+			"1.11.2",
+			"1.11.2",
+			[]string{`docker tag "1.11.2" does not match version "unknown"`, `git tag "1.11.2" does not match version "unknown"`},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			DockerInfo = DockerBuildInfo{Hub: "docker.io/istio", Tag: v.dockerTag}
+			err := (BuildInfo{Version: v.version, GitTag: v.gitTag}).SelfCheck()
+			if len(v.wantErrors) == 0 {
+				if err != nil {
+					t.Errorf("Got %v, expected success", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected failure, got success")
+			}
+			for _, want := range v.wantErrors {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("got %q; want it to contain %q", err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestSelfCheckWith(t *testing.T) {
+	defer Snapshot()()
+	DockerInfo = DockerBuildInfo{Hub: "docker.io/istio", Tag: "1.12.0"}
+
+	remote := BuildInfo{Version: "1.11.2", GitTag: "1.11.2"}
+	if err := remote.SelfCheckWith(DockerBuildInfo{Hub: "docker.io/istio", Tag: "1.11.2"}); err != nil {
+		t.Errorf("Got %v, expected success", err)
+	}
+	if err := remote.SelfCheckWith(DockerBuildInfo{Hub: "docker.io/istio", Tag: "1.11.1"}); err == nil {
+		t.Errorf("Expected failure, got success")
+	}
+}
+
+func TestIsCIBuild(t *testing.T) {
+	cases := []struct {
+		name string
+		in   BuildInfo
+		want bool
+	}{
+		{"ci", BuildInfo{Source: "ci"}, true},
+		{"ci upper", BuildInfo{Source: "CI"}, true},
+		{"local", BuildInfo{Source: "local"}, false},
+		{"unset", BuildInfo{}, false},
+		{"init", Info, false},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.in.IsCIBuild(); got != v.want {
+				t.Errorf("got %v; want %v", got, v.want)
+			}
+		})
+	}
+}